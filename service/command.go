@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+// runCommand dispatches the maintenance command given after the flags, ex.
+//   fs -config config.yaml rekey -layout 3 -dry-run
+func runCommand(args []string) error {
+	switch args[0] {
+	case "rekey":
+		return runRekeyCommand(args[1:])
+	case "backfill-uuids":
+		return runBackfillUUIDsCommand()
+	}
+
+	return fmt.Errorf("Invalid command: %s, expecting: rekey, backfill-uuids", args[0])
+}
+
+// runBackfillUUIDsCommand assigns the public ids to the files created before
+// they were introduced
+func runBackfillUUIDsCommand() error {
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		return err
+	}
+	defer dbrep.Close()
+
+	count, err := dbrep.BackfillFileUUIDs()
+	if err != nil {
+		return err
+	}
+	config.Log.Info("Backfilled file uuids: " + fmt.Sprintf("%d", count))
+
+	return nil
+}
+
+// runRekeyCommand moves the objects to the keys of the layout, by default
+// the configured one
+func runRekeyCommand(args []string) error {
+	cmd := flag.NewFlagSet("rekey", flag.ContinueOnError)
+	layout := cmd.Int("layout", config.Setup.ObjectKeyLayout, "object key layout: 1, 2, 3")
+	dryRun := cmd.Bool("dry-run", false, "only report the files to be moved")
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	moved, err := store.RekeyFileObjects(context.Background(), *layout, *dryRun, nil)
+	config.Log.Info("Rekeyed files: " + fmt.Sprintf("%d", moved))
+
+	return err
+}
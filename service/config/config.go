@@ -10,6 +10,7 @@ var (
 	Setup             *SetupValueSet
 	configFileNamePtr *string
 	listenerOnlyMode  bool
+	commandArgs       []string
 )
 
 // checkCmdLineArgs detects usage of cmd line args like -v (version info)
@@ -28,6 +29,9 @@ func checkCmdLineArgs() {
 	if *listener {
 		listenerOnlyMode = true
 	}
+
+	// The remaining args are the maintenance command with its own flags
+	commandArgs = flag.Args()
 }
 
 // Init gets the contents of file and uses it to make a config
@@ -48,6 +52,7 @@ func Init(gitCommitHash, builtAt, builtBy, builtOn string) {
 
 	Setup.ConfigFileName = *configFileNamePtr
 	Setup.ListenerOnlyMode = listenerOnlyMode
+	Setup.CommandArgs = commandArgs
 	InitLogger(Setup.LogFormat, Setup.LogLevel)
 	Setup.LogSetup()
 }
@@ -13,4 +13,26 @@ const (
 	DEFAULT_PRESIGNED_DUR_MINS      = 60
 	DEFAULT_OBJECT_NAMESPACE_MODULE = 10
 	DEFAULT_CHECKSUM_TYPE           = "SHA256"
+	DEFAULT_MULTIPART_PART_SIZE_MB  = 64
+	DEFAULT_BUCKET_SPACE_SIZE       = 1
+	DEFAULT_BUCKET_SHARD_KEY        = "tenant"
+	DEFAULT_OBJECT_KEY_LAYOUT       = 1
+	DEFAULT_PRESIGN_LIMITS          = "get=60-86400,put=60-604800,head=60-86400"
+	DEFAULT_DOWNLOAD_LINK_TTL_SEC   = 86400
+	DEFAULT_DOWNLOAD_LINK_PRESIGN   = 60
+	DEFAULT_PROXY_TIMEOUT_MIN       = 60
+	DEFAULT_BUNDLE_MAX_FILES        = 1000
+	DEFAULT_JOB_WORKERS             = 1
+	DEFAULT_JOB_LEASE_SEC           = 60
+	DEFAULT_JOB_POLL_SEC            = 5
+	DEFAULT_JOB_MAX_ATTEMPTS        = 3
+	DEFAULT_JOB_BACKOFF_SEC         = 30
+	DEFAULT_RETENTION_INTERVAL_MIN  = 60
+	DEFAULT_RETENTION_MAX_DELETES   = 1000
+	DEFAULT_OBJECT_LOCK             = false
+	DEFAULT_OBJECT_VERSIONING       = false
+	DEFAULT_DEDUP                   = false
+	DEFAULT_BATCH_MAX_FILES         = 100
+	DEFAULT_AUDIT_SINK              = ""
+	DEFAULT_ALLOWED_CONTENT_TYPES   = "application/octet-stream,application/json,application/gzip,application/zip,application/x-tar,application/pdf,text/plain,text/csv,image/png,image/jpeg"
 )
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxPresignDuration is the longest validity of the url signed with SigV4
+const MaxPresignDuration = 7 * 24 * time.Hour
+
+// PresignLimit bounds the validity of the presigned url of a method
+type PresignLimit struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// PresignLimits are the bounds by the method: get, put, head
+type PresignLimits map[string]PresignLimit
+
+// ParsePresignLimits reads the limits in the form of comma separated
+// method=min-max entries with the durations in seconds, ex.
+// get=60-3600,put=300-86400
+func ParsePresignLimits(val string) (PresignLimits, error) {
+	limits := make(PresignLimits)
+	if strings.TrimSpace(val) == "" {
+		return limits, nil
+	}
+
+	for _, entry := range strings.Split(val, ",") {
+		method, bounds, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			return nil, fmt.Errorf("Invalid presign limit: %s, expected: method=min-max", entry)
+		}
+		method = strings.ToLower(method)
+		switch method {
+		case "get", "put", "head":
+		default:
+			return nil, fmt.Errorf("Invalid presign limit method: %s, expected: get, put, head", method)
+		}
+
+		minStr, maxStr, found := strings.Cut(bounds, "-")
+		if !found {
+			return nil, fmt.Errorf("Invalid presign limit: %s, expected: method=min-max", entry)
+		}
+		min, err := strconv.ParseInt(minStr, 10, 64)
+		if err != nil || min <= 0 {
+			return nil, fmt.Errorf("Invalid presign limit minimum: %s", entry)
+		}
+		max, err := strconv.ParseInt(maxStr, 10, 64)
+		if err != nil || max < min || time.Duration(max)*time.Second > MaxPresignDuration {
+			return nil, fmt.Errorf("Invalid presign limit maximum: %s, expected: %d..%d",
+				entry, min, int64(MaxPresignDuration/time.Second))
+		}
+
+		limits[method] = PresignLimit{
+			Min: time.Duration(min) * time.Second,
+			Max: time.Duration(max) * time.Second,
+		}
+	}
+
+	return limits, nil
+}
+
+// String is the form the limits are parsed from
+func (l PresignLimits) String() string {
+	var entries []string
+	for method, limit := range l {
+		entries = append(entries, fmt.Sprintf("%s=%d-%d", method,
+			int64(limit.Min/time.Second), int64(limit.Max/time.Second)))
+	}
+	sort.Strings(entries)
+
+	return strings.Join(entries, ",")
+}
@@ -15,6 +15,7 @@ import (
 type SetupValueSet struct {
 	ConfigFileName        string
 	ListenerOnlyMode      bool
+	CommandArgs           []string
 	LogLevel              string
 	LogFormat             string
 	UseFileStore          string
@@ -27,6 +28,29 @@ type SetupValueSet struct {
 	SQLMaxOpenConns       int
 	SQLMaxLifetime        time.Duration
 	PresignDurMins        time.Duration
+	PresignLimits         PresignLimits
+	DownloadLinkTTL       time.Duration
+	DownloadLinkPresign   time.Duration
+	ProxyMode             bool
+	ProxyTimeout          time.Duration
+	BundleMaxFiles        int
+	JobWorkers            int
+	JobLease              time.Duration
+	JobPoll               time.Duration
+	JobMaxAttempts        int
+	JobBackoff            time.Duration
+	RetentionInterval     time.Duration
+	RetentionMaxDeletes   int
+	ObjectLock            bool
+	ObjectVersioning      bool
+	Dedup                 bool
+	BatchMaxFiles         int
+	AuditSink             string
+	MultipartPartSize     int64
+	BucketSpaceSize       uint
+	BucketShardKey        string
+	ObjectKeyLayout       int
+	AllowedContentTypes   []string
 }
 
 // LogSetup show initial start info with the setup of env
@@ -48,6 +72,29 @@ func (s *SetupValueSet) LogSetup() {
 	Log.Info("          Use File Store: " + s.UseFileStore)
 	Log.Info("            Use Listener: " + strconv.FormatBool(s.UseListener))
 	Log.Info("           Checksum Type: " + s.CheckSumType)
+	Log.Info("     Multipart Part Size: " + fmt.Sprintf("%d bytes", s.MultipartPartSize))
+	Log.Info("       Bucket Space Size: " + fmt.Sprintf("%d", s.BucketSpaceSize))
+	Log.Info("        Bucket Shard Key: " + s.BucketShardKey)
+	Log.Info("       Object Key Layout: " + fmt.Sprintf("%d", s.ObjectKeyLayout))
+	Log.Info("          Presign Limits: " + s.PresignLimits.String())
+	Log.Info("       Download Link TTL: " + s.DownloadLinkTTL.String())
+	Log.Info("   Download Link Presign: " + s.DownloadLinkPresign.String())
+	Log.Info("              Proxy Mode: " + strconv.FormatBool(s.ProxyMode))
+	Log.Info("           Proxy Timeout: " + s.ProxyTimeout.String())
+	Log.Info("        Bundle Max Files: " + fmt.Sprintf("%d", s.BundleMaxFiles))
+	Log.Info("             Job Workers: " + fmt.Sprintf("%d", s.JobWorkers))
+	Log.Info("               Job Lease: " + s.JobLease.String())
+	Log.Info("                Job Poll: " + s.JobPoll.String())
+	Log.Info("        Job Max Attempts: " + fmt.Sprintf("%d", s.JobMaxAttempts))
+	Log.Info("             Job Backoff: " + s.JobBackoff.String())
+	Log.Info("      Retention Interval: " + s.RetentionInterval.String())
+	Log.Info("   Retention Max Deletes: " + fmt.Sprintf("%d", s.RetentionMaxDeletes))
+	Log.Info("             Object Lock: " + strconv.FormatBool(s.ObjectLock))
+	Log.Info("       Object Versioning: " + strconv.FormatBool(s.ObjectVersioning))
+	Log.Info("                   Dedup: " + strconv.FormatBool(s.Dedup))
+	Log.Info("         Batch Max Files: " + fmt.Sprintf("%d", s.BatchMaxFiles))
+	Log.Info("              Audit Sink: " + s.AuditSink)
+	Log.Info("   Allowed Content Types: " + strings.Join(s.AllowedContentTypes, ","))
 	
 	// Server parameters
 	Log.Info("    HTTP ServerIPAddress: " + s.ServerIPAddress)
@@ -101,6 +148,28 @@ func (s *SetupValueSet) initDefaultValues() {
 	s.CheckSumType = DEFAULT_CHECKSUM_TYPE
 	s.ObjectNamespaceModule = DEFAULT_OBJECT_NAMESPACE_MODULE
 	s.PresignDurMins = DEFAULT_PRESIGNED_DUR_MINS
+	s.MultipartPartSize = DEFAULT_MULTIPART_PART_SIZE_MB << 20
+	s.BucketSpaceSize = DEFAULT_BUCKET_SPACE_SIZE
+	s.BucketShardKey = DEFAULT_BUCKET_SHARD_KEY
+	s.ObjectKeyLayout = DEFAULT_OBJECT_KEY_LAYOUT
+	s.PresignLimits, _ = ParsePresignLimits(DEFAULT_PRESIGN_LIMITS)
+	s.DownloadLinkTTL = DEFAULT_DOWNLOAD_LINK_TTL_SEC * time.Second
+	s.DownloadLinkPresign = DEFAULT_DOWNLOAD_LINK_PRESIGN * time.Second
+	s.ProxyTimeout = DEFAULT_PROXY_TIMEOUT_MIN * time.Minute
+	s.BundleMaxFiles = DEFAULT_BUNDLE_MAX_FILES
+	s.JobWorkers = DEFAULT_JOB_WORKERS
+	s.JobLease = DEFAULT_JOB_LEASE_SEC * time.Second
+	s.JobPoll = DEFAULT_JOB_POLL_SEC * time.Second
+	s.JobMaxAttempts = DEFAULT_JOB_MAX_ATTEMPTS
+	s.JobBackoff = DEFAULT_JOB_BACKOFF_SEC * time.Second
+	s.RetentionInterval = DEFAULT_RETENTION_INTERVAL_MIN * time.Minute
+	s.RetentionMaxDeletes = DEFAULT_RETENTION_MAX_DELETES
+	s.ObjectLock = DEFAULT_OBJECT_LOCK
+	s.ObjectVersioning = DEFAULT_OBJECT_VERSIONING
+	s.Dedup = DEFAULT_DEDUP
+	s.BatchMaxFiles = DEFAULT_BATCH_MAX_FILES
+	s.AuditSink = DEFAULT_AUDIT_SINK
+	s.AllowedContentTypes = strings.Split(DEFAULT_ALLOWED_CONTENT_TYPES, ",")
 	s.ServerIPAddress = DEFAULT_IP_ADDRESS
 	s.ServerPort = DEFAULT_PORT
 	s.SQLMaxIdleConns = DEFAULT_SQL_MAX_IDLE_CONNS
@@ -158,6 +227,223 @@ func (s *SetupValueSet) setEnvValues() error {
 		s.ObjectNamespaceModule = uint(valuint64)
 	}
 
+	val = os.Getenv("USE_MULTIPART_PART_SIZE_MB")
+	if val != "" {
+		valint64, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || valint64 <= 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_MULTIPART_PART_SIZE_MB", val)
+		}
+
+		s.MultipartPartSize = valint64 << 20
+	}
+
+	val = os.Getenv("USE_BUCKET_SPACE_SIZE")
+	if val != "" {
+		valuint64, err := strconv.ParseUint(val, 10, 64)
+		if err != nil || valuint64 == 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_BUCKET_SPACE_SIZE", val)
+		}
+
+		s.BucketSpaceSize = uint(valuint64)
+	}
+
+	val = os.Getenv("USE_BUCKET_SHARD_KEY")
+	if val != "" {
+		switch val {
+		case "tenant", "id":
+			s.BucketShardKey = val
+		default:
+			return fmt.Errorf("Invalid env variable %s value: %s, expected: tenant, id", "USE_BUCKET_SHARD_KEY", val)
+		}
+	}
+
+	val = os.Getenv("USE_OBJECT_KEY_LAYOUT")
+	if val != "" {
+		valint, err := strconv.Atoi(val)
+		if err != nil || valint < 1 || valint > 3 {
+			return fmt.Errorf("Invalid env variable %s value: %s, expected: 1, 2, 3", "USE_OBJECT_KEY_LAYOUT", val)
+		}
+
+		s.ObjectKeyLayout = valint
+	}
+
+	val = os.Getenv("USE_PRESIGN_LIMITS")
+	if val != "" {
+		limits, err := ParsePresignLimits(val)
+		if err != nil {
+			return fmt.Errorf("Invalid env variable %s value: %s - %s", "USE_PRESIGN_LIMITS", val, err)
+		}
+
+		s.PresignLimits = limits
+	}
+
+	val = os.Getenv("USE_DOWNLOAD_LINK_TTL_SEC")
+	if val != "" {
+		valint64, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || valint64 <= 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_DOWNLOAD_LINK_TTL_SEC", val)
+		}
+
+		s.DownloadLinkTTL = time.Duration(valint64) * time.Second
+	}
+
+	val = os.Getenv("USE_DOWNLOAD_LINK_PRESIGN_SEC")
+	if val != "" {
+		valint64, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || valint64 <= 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_DOWNLOAD_LINK_PRESIGN_SEC", val)
+		}
+
+		s.DownloadLinkPresign = time.Duration(valint64) * time.Second
+	}
+
+	val = os.Getenv("USE_PROXY_MODE")
+	if val != "" {
+		if val == "true" {
+			s.ProxyMode = true
+		}
+	}
+
+	val = os.Getenv("USE_PROXY_TIMEOUT_MIN")
+	if val != "" {
+		valint64, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || valint64 <= 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_PROXY_TIMEOUT_MIN", val)
+		}
+
+		s.ProxyTimeout = time.Duration(valint64) * time.Minute
+	}
+
+	val = os.Getenv("USE_BUNDLE_MAX_FILES")
+	if val != "" {
+		valint, err := strconv.Atoi(val)
+		if err != nil || valint <= 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_BUNDLE_MAX_FILES", val)
+		}
+
+		s.BundleMaxFiles = valint
+	}
+
+	val = os.Getenv("USE_JOB_WORKERS")
+	if val != "" {
+		valint, err := strconv.Atoi(val)
+		if err != nil || valint < 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_JOB_WORKERS", val)
+		}
+
+		s.JobWorkers = valint
+	}
+
+	val = os.Getenv("USE_JOB_LEASE_SEC")
+	if val != "" {
+		valint64, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || valint64 <= 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_JOB_LEASE_SEC", val)
+		}
+
+		s.JobLease = time.Duration(valint64) * time.Second
+	}
+
+	val = os.Getenv("USE_JOB_POLL_SEC")
+	if val != "" {
+		valint64, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || valint64 <= 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_JOB_POLL_SEC", val)
+		}
+
+		s.JobPoll = time.Duration(valint64) * time.Second
+	}
+
+	val = os.Getenv("USE_JOB_MAX_ATTEMPTS")
+	if val != "" {
+		valint, err := strconv.Atoi(val)
+		if err != nil || valint <= 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_JOB_MAX_ATTEMPTS", val)
+		}
+
+		s.JobMaxAttempts = valint
+	}
+
+	val = os.Getenv("USE_JOB_BACKOFF_SEC")
+	if val != "" {
+		valint64, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || valint64 <= 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_JOB_BACKOFF_SEC", val)
+		}
+
+		s.JobBackoff = time.Duration(valint64) * time.Second
+	}
+
+	val = os.Getenv("USE_RETENTION_INTERVAL_MIN")
+	if val != "" {
+		valint64, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || valint64 < 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_RETENTION_INTERVAL_MIN", val)
+		}
+
+		s.RetentionInterval = time.Duration(valint64) * time.Minute
+	}
+
+	val = os.Getenv("USE_RETENTION_MAX_DELETES")
+	if val != "" {
+		valint, err := strconv.Atoi(val)
+		if err != nil || valint <= 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_RETENTION_MAX_DELETES", val)
+		}
+
+		s.RetentionMaxDeletes = valint
+	}
+
+	val = os.Getenv("USE_OBJECT_LOCK")
+	if val != "" {
+		if val == "true" {
+			s.ObjectLock = true
+		}
+	}
+
+	val = os.Getenv("USE_OBJECT_VERSIONING")
+	if val != "" {
+		if val == "true" {
+			s.ObjectVersioning = true
+		}
+	}
+
+	val = os.Getenv("USE_DEDUP")
+	if val != "" {
+		if val == "true" {
+			s.Dedup = true
+		}
+	}
+
+	val = os.Getenv("USE_BATCH_MAX_FILES")
+	if val != "" {
+		valint, err := strconv.Atoi(val)
+		if err != nil || valint <= 0 {
+			return fmt.Errorf("Invalid env variable %s value: %s", "USE_BATCH_MAX_FILES", val)
+		}
+
+		s.BatchMaxFiles = valint
+	}
+
+	val = os.Getenv("USE_AUDIT_SINK")
+	if val != "" {
+		s.AuditSink = val
+	}
+
+	val = os.Getenv("USE_ALLOWED_CONTENT_TYPES")
+	if val != "" {
+		var types []string
+		for _, t := range strings.Split(val, ",") {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if t == "" || !strings.Contains(t, "/") {
+				return fmt.Errorf("Invalid env variable %s value: %s", "USE_ALLOWED_CONTENT_TYPES", val)
+			}
+			types = append(types, t)
+		}
+
+		s.AllowedContentTypes = types
+	}
+
 	val = os.Getenv("HTTP_ADDRESS")
 	if val != "" {
 		s.ServerIPAddress = val
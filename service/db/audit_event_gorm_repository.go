@@ -0,0 +1,80 @@
+package db
+
+import (
+	"sync"
+
+	"gorm.io/gorm"
+
+	. "fs/service/config"
+)
+
+var (
+	auditProtectOnce sync.Once
+)
+
+// protectAuditEvents makes the table of the audit events append only by the
+// rules ignoring the updates and the removals of the rows. It is done once
+// per process after the migration of the schema.
+func protectAuditEvents(gormdb *gorm.DB) {
+	auditProtectOnce.Do(func() {
+		for _, rule := range []string{
+			"CREATE OR REPLACE RULE audit_events_no_update AS ON UPDATE TO audit_events DO INSTEAD NOTHING",
+			"CREATE OR REPLACE RULE audit_events_no_delete AS ON DELETE TO audit_events DO INSTEAD NOTHING",
+		} {
+			result := gormdb.Exec(rule)
+			if result.Error != nil {
+				Log.Error("Error protecting audit events: " + result.Error.Error())
+				return
+			}
+		}
+		Log.Debug("Protected audit events from updates and removals")
+	})
+}
+
+// CreateAuditEvents - appends the events
+func (r FileStoreRepositoryGORM) CreateAuditEvents(aes []AuditEvent) (int64, error) {
+	if len(aes) == 0 {
+		return 0, nil
+	}
+	result := r.gormdb.Create(&aes)
+
+	return result.RowsAffected, result.Error
+}
+
+// ReadAuditEvents - the events matching the filter, the latest first, at most
+// the limit of them before the id of the filter if given
+func (r FileStoreRepositoryGORM) ReadAuditEvents(af AuditFilter, limit int) ([]AuditEvent, int64, error) {
+	query := r.gormdb.Model(&AuditEvent{})
+	if af.Action != "" {
+		query = query.Where("action = ?", af.Action)
+	}
+	if af.Principal != "" {
+		query = query.Where("principal = ?", af.Principal)
+	}
+	if af.TenantID != "" {
+		query = query.Where("tenant_id = ?", af.TenantID)
+	}
+	if af.DeviceID != "" {
+		query = query.Where("device_id = ?", af.DeviceID)
+	}
+	if af.FileID != "" {
+		query = query.Where("file_id = ?", af.FileID)
+	}
+	if af.RequestID != "" {
+		query = query.Where("request_id = ?", af.RequestID)
+	}
+	if af.From != nil {
+		query = query.Where("created_at >= ?", *af.From)
+	}
+	if af.To != nil {
+		query = query.Where("created_at < ?", *af.To)
+	}
+	if af.Before != 0 {
+		query = query.Where("id < ?", af.Before)
+	}
+
+	var aes []AuditEvent
+	result := query.Order("id DESC").Limit(limit).Find(&aes)
+
+	return aes, result.RowsAffected, result.Error
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"time"
+)
+
+// Audit event of the action on the files or of the admin action, ex. the
+// presign of the GET url by the principal of the token. The events are only
+// ever appended, the table refuses the updates and the removals.
+// The status is the one of the reply, the failed attempts are recorded too.
+// The source ip is the peer of the connection, the forwarded for is the
+// header given by the proxy in front if any.
+type AuditEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	Action       string `gorm:"index" json:"action"`
+	Method       string `json:"method,omitempty"`
+	Route        string `json:"route,omitempty"`
+	Path         string `json:"path,omitempty"`
+	Principal    string `gorm:"index" json:"principal,omitempty"`
+	TenantID     string `gorm:"index:idx_audit_tenant_device" json:"tenant_id,omitempty"`
+	DeviceID     string `gorm:"index:idx_audit_tenant_device" json:"device_id,omitempty"`
+	FileID       string `gorm:"index" json:"file_id,omitempty"`
+	SourceIP     string `json:"source_ip,omitempty"`
+	ForwardedFor string `json:"forwarded_for,omitempty"`
+	RequestID    string `gorm:"index" json:"request_id,omitempty"`
+	Status       int    `json:"status,omitempty"`
+	Detail       string `json:"detail,omitempty"`
+}
+
+// Audit filter of the events to be read, the empty attributes match any
+type AuditFilter struct {
+	Action    string
+	Principal string
+	TenantID  string
+	DeviceID  string
+	FileID    string
+	RequestID string
+	From      *time.Time
+	To        *time.Time
+	Before    uint
+}
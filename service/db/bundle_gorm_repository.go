@@ -0,0 +1,115 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// CreateBundle - the pending bundle of the files matching the filter, the device,
+// the status and the time range are optional
+func (r FileStoreRepositoryGORM) CreateBundle(tid, did, status string, from, to *time.Time, format string) ([]Bundle, int64, error) {
+	uuid, err := NewUUID()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	b := Bundle{
+		UUID:     &uuid,
+		TenantID: tid,
+		DeviceID: did,
+		Status:   status,
+		From:     from,
+		To:       to,
+		Format:   format,
+		State:    "P",
+	}
+	result := r.gormdb.Create(&b)
+	bs := make([]Bundle, 1)
+	bs[0] = b
+
+	return bs, result.RowsAffected, result.Error
+}
+
+// ReadBundleById - unique key read by the public UUID of the bundle
+func (r FileStoreRepositoryGORM) ReadBundleById(id string) ([]Bundle, int64, error) {
+	if !IsUUID(id) {
+		return nil, 0, fmt.Errorf("Invalid id: %s", id)
+	}
+
+	var bs []Bundle
+	result := r.gormdb.Where("uuid = ?", id).Find(&bs)
+
+	return bs, result.RowsAffected, result.Error
+}
+
+// SetBundleJob links the bundle to the job producing it
+func (r FileStoreRepositoryGORM) SetBundleJob(id uint, jobId string) error {
+	result := r.gormdb.Model(&Bundle{}).Where("id = ?", id).Update("job_id", jobId)
+
+	return result.Error
+}
+
+// SetBundleState records the state of the bundle with the error if it failed
+func (r FileStoreRepositoryGORM) SetBundleState(id uint, state, message string) error {
+	result := r.gormdb.Model(&Bundle{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"state": state,
+		"error": message,
+	})
+
+	return result.Error
+}
+
+// SetBundleProgress records the number of the files put into the bundle so far
+func (r FileStoreRepositoryGORM) SetBundleProgress(id uint, fileCount, filesDone int64) error {
+	result := r.gormdb.Model(&Bundle{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"file_count": fileCount,
+		"files_done": filesDone,
+	})
+
+	return result.Error
+}
+
+// CompleteBundle links the bundle to the file of its archive
+func (r FileStoreRepositoryGORM) CompleteBundle(id, fileId uint, size int64) error {
+	result := r.gormdb.Model(&Bundle{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"state":   "D",
+		"file_id": fileId,
+		"size":    size,
+	})
+
+	return result.Error
+}
+
+// DeleteBundleFile removes the archive file of the failed run of the bundle,
+// the file of the bundle not done is never given out so it is removed for good
+func (r FileStoreRepositoryGORM) DeleteBundleFile(id uint) error {
+	result := r.gormdb.Unscoped().Delete(&FileStore{}, id)
+
+	return result.Error
+}
+
+// ReadFilesForBundle - the uploaded files of the tenant matching the filter in
+// the order of their creation, the archives of the other bundles excluded. The
+// device and the status are matched when given, the time range is the one of
+// the creation of the files.
+func (r FileStoreRepositoryGORM) ReadFilesForBundle(tid, did, status string, from, to *time.Time, limit int) ([]FileStore, int64, error) {
+	query := r.gormdb.Where("tenant_id = ? AND status NOT IN ?", tid, []string{"N", "M"}).
+		Where("(metadata IS NULL OR NOT metadata @> ?::jsonb)", Metadata{"category": "bundle"})
+	if did != "" {
+		query = query.Where("device_id = ?", did)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if from != nil {
+		query = query.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("created_at < ?", *to)
+	}
+
+	var fss []FileStore
+	result := query.Order("id").Limit(limit).Find(&fss)
+
+	return fss, result.RowsAffected, result.Error
+}
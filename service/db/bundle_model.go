@@ -0,0 +1,36 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Bundle of the files selected by the filter put into a single zip or tar.gz
+// archive produced in the background. The state goes: (P)ending -> (R)unning
+// -> (D)one or (F)ailed with the error. The archive is the file of the bundle
+// category, its GET access is given once the bundle is done. The bundle is
+// produced by the job of the bundle type.
+type Bundle struct {
+	ID        uint    `gorm:"primarykey" json:"-"`
+	UUID      *string `gorm:"type:uuid;uniqueIndex" json:"id,omitempty"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	TenantID  string      `gorm:"index" json:"tenant_id"`
+	DeviceID  string      `json:"device_id,omitempty"`
+	Status    string      `json:"status,omitempty"`
+	From      *time.Time  `json:"from,omitempty"`
+	To        *time.Time  `json:"to,omitempty"`
+	Format    string      `json:"format"`
+	State     string      `json:"state"`
+	FileCount int64       `json:"file_count"`
+	FilesDone int64       `json:"files_done"`
+	Size      int64       `json:"size,omitempty"`
+	FileID    uint        `json:"-"`
+	File      *string     `gorm:"-" json:"file_id,omitempty"`
+	JobID     string      `json:"job_id,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Access    *FileAccess `gorm:"-" json:"access,omitempty"`
+}
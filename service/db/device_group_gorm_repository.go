@@ -0,0 +1,269 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// CreateGroup - new named group of devices in the tenant
+func (r FileStoreRepositoryGORM) CreateGroup(tid, name string) ([]DeviceGroup, int64, error) {
+	if tid == "" || name == "" {
+		return nil, 0, fmt.Errorf("Invalid group, empty tenant or name")
+	}
+
+	dg := DeviceGroup{
+		TenantID: tid,
+		Name:     name,
+	}
+	result := r.gormdb.Create(&dg)
+	dgs := make([]DeviceGroup, 1)
+	dgs[0] = dg
+
+	return dgs, result.RowsAffected, result.Error
+}
+
+// ReadGroupById - primary key read by ID of the group
+func (r FileStoreRepositoryGORM) ReadGroupById(id string) ([]DeviceGroup, int64, error) {
+	idpkval, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var dg DeviceGroup
+	result := r.gormdb.First(&dg, idpkval)
+	dgs := make([]DeviceGroup, 1)
+	dgs[0] = dg
+
+	return dgs, result.RowsAffected, result.Error
+}
+
+// ReadGroupsByTenant - all groups defined in the tenant
+func (r FileStoreRepositoryGORM) ReadGroupsByTenant(tid string) ([]DeviceGroup, int64, error) {
+	var dgs []DeviceGroup
+	result := r.gormdb.Where("tenant_id = ?", tid).Find(&dgs)
+
+	return dgs, result.RowsAffected, result.Error
+}
+
+// DeleteGroupById - removes the group with all its memberships and file assignments
+func (r FileStoreRepositoryGORM) DeleteGroupById(id string) (int64, error) {
+	idpkval, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	err = r.gormdb.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("group_id = ?", idpkval).Delete(&DeviceGroupMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		result = tx.Unscoped().Where("group_id = ?", idpkval).Delete(&FileGroupAssignment{})
+		if result.Error != nil {
+			return result.Error
+		}
+		result = tx.Unscoped().Delete(&DeviceGroup{}, idpkval)
+		rows = result.RowsAffected
+		return result.Error
+	})
+
+	return rows, err
+}
+
+// AddGroupMember - makes the device a member of the group
+func (r FileStoreRepositoryGORM) AddGroupMember(gid, did string) ([]DeviceGroupMember, int64, error) {
+	gidval, err := strconv.ParseUint(gid, 10, 64)
+	if err != nil {
+		return nil, 0, err
+	}
+	if did == "" {
+		return nil, 0, fmt.Errorf("Invalid device, empty string")
+	}
+
+	dgm := DeviceGroupMember{
+		GroupID:  uint(gidval),
+		DeviceID: did,
+	}
+	result := r.gormdb.Create(&dgm)
+	dgms := make([]DeviceGroupMember, 1)
+	dgms[0] = dgm
+
+	return dgms, result.RowsAffected, result.Error
+}
+
+// ReadGroupMembers - all devices being members of the group
+func (r FileStoreRepositoryGORM) ReadGroupMembers(gid string) ([]DeviceGroupMember, int64, error) {
+	gidval, err := strconv.ParseUint(gid, 10, 64)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var dgms []DeviceGroupMember
+	result := r.gormdb.Where("group_id = ?", gidval).Find(&dgms)
+
+	return dgms, result.RowsAffected, result.Error
+}
+
+// DeleteGroupMember - removes the device from the group
+func (r FileStoreRepositoryGORM) DeleteGroupMember(gid, did string) (int64, error) {
+	gidval, err := strconv.ParseUint(gid, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	result := r.gormdb.Unscoped().
+		Where("group_id = ? AND device_id = ?", gidval, did).
+		Delete(&DeviceGroupMember{})
+
+	return result.RowsAffected, result.Error
+}
+
+// AssignFileToGroup - limits the availability of the file to the members of the group
+func (r FileStoreRepositoryGORM) AssignFileToGroup(fid, gid string) ([]FileGroupAssignment, int64, error) {
+	fidval, err := r.filePrimaryKey(fid)
+	if err != nil {
+		return nil, 0, err
+	}
+	gidval, err := strconv.ParseUint(gid, 10, 64)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Both sides must exist and belong to the same tenant
+	var fs FileStore
+	result := r.gormdb.First(&fs, fidval)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	var dg DeviceGroup
+	result = r.gormdb.First(&dg, gidval)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	if fs.TenantID != dg.TenantID {
+		return nil, 0, fmt.Errorf("Group %d does not belong to the tenant of file %s", gidval, fid)
+	}
+
+	fga := FileGroupAssignment{
+		FileID:   fidval,
+		GroupID:  uint(gidval),
+		FileUUID: fid,
+	}
+	result = r.gormdb.Create(&fga)
+	fgas := make([]FileGroupAssignment, 1)
+	fgas[0] = fga
+
+	return fgas, result.RowsAffected, result.Error
+}
+
+// ReadFileGroupAssignments - all groups the file is assigned to
+func (r FileStoreRepositoryGORM) ReadFileGroupAssignments(fid string) ([]FileGroupAssignment, int64, error) {
+	fidval, err := r.filePrimaryKey(fid)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var fgas []FileGroupAssignment
+	result := r.gormdb.Where("file_id = ?", fidval).Find(&fgas)
+	for i := range fgas {
+		fgas[i].FileUUID = fid
+	}
+
+	return fgas, result.RowsAffected, result.Error
+}
+
+// DeleteFileGroupAssignment - removes the assignment of the file to the group
+func (r FileStoreRepositoryGORM) DeleteFileGroupAssignment(fid, gid string) (int64, error) {
+	fidval, err := r.filePrimaryKey(fid)
+	if err != nil {
+		return 0, err
+	}
+	gidval, err := strconv.ParseUint(gid, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	result := r.gormdb.Unscoped().
+		Where("file_id = ? AND group_id = ?", fidval, gidval).
+		Delete(&FileGroupAssignment{})
+
+	return result.RowsAffected, result.Error
+}
+
+// ReadAvailableFiles - files of the tenant available to the device the same
+// way as checked by IsFileAvailable: the ones it owns, the ones without group
+// assignments and the ones assigned to any group the device is a member of
+func (r FileStoreRepositoryGORM) ReadAvailableFiles(tid, did string) ([]FileStore, int64, error) {
+	if did == "" {
+		return nil, 0, nil
+	}
+
+	var fss []FileStore
+	result := r.gormdb.
+		Where("tenant_id = ?", tid).
+		Where(r.gormdb.Where("device_id = ?", did).
+			Or("id NOT IN (?)", r.gormdb.Model(&FileGroupAssignment{}).
+				Select("file_id")).
+			Or("id IN (?)", r.gormdb.Model(&FileGroupAssignment{}).
+				Select("file_group_assignments.file_id").
+				Joins("JOIN device_group_members ON device_group_members.group_id = file_group_assignments.group_id").
+				Where("device_group_members.device_id = ?", did))).
+		Find(&fss)
+
+	return fss, result.RowsAffected, result.Error
+}
+
+// IsFileAvailable - checks if the device of the tenant of the file may access
+// the file as decided by FileAvailable with the groups the file is assigned to
+// and the ones of them the device is a member of.
+func (r FileStoreRepositoryGORM) IsFileAvailable(fs FileStore, did string) (bool, error) {
+	if did == "" || fs.DeviceID == did {
+		return FileAvailable(fs, did, nil, nil), nil
+	}
+
+	var groups []uint
+	result := r.gormdb.Model(&FileGroupAssignment{}).
+		Where("file_id = ?", fs.ID).
+		Pluck("group_id", &groups)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	var memberOf []uint
+	if len(groups) > 0 {
+		result = r.gormdb.Model(&DeviceGroupMember{}).
+			Where("device_id = ? AND group_id IN ?", did, groups).
+			Pluck("group_id", &memberOf)
+		if result.Error != nil {
+			return false, result.Error
+		}
+	}
+
+	return FileAvailable(fs, did, groups, memberOf), nil
+}
+
+// FileAvailable - the file is available to the device which owns it, to any
+// device of the tenant if it is not assigned to any group, otherwise to the
+// members of the assigned groups only. The device must be given.
+func FileAvailable(fs FileStore, did string, groups, memberOf []uint) bool {
+	if did == "" {
+		return false
+	}
+	if fs.DeviceID == did || len(groups) == 0 {
+		return true
+	}
+
+	assigned := make(map[uint]bool, len(groups))
+	for _, gid := range groups {
+		assigned[gid] = true
+	}
+	for _, gid := range memberOf {
+		if assigned[gid] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"gorm.io/gorm"
+)
+
+// Named group of devices within a tenant, ex. a canary ring. The files may be
+// assigned to the groups so that only the member devices can get them.
+type DeviceGroup struct {
+	gorm.Model
+
+	TenantID string `gorm:"uniqueIndex:idx_group_tenant_name" json:"tenant_id,omitempty"`
+	Name     string `gorm:"uniqueIndex:idx_group_tenant_name" json:"name,omitempty"`
+}
+
+// Membership of a device in a device group.
+type DeviceGroupMember struct {
+	gorm.Model
+
+	GroupID  uint   `gorm:"uniqueIndex:idx_group_member" json:"group_id,omitempty"`
+	DeviceID string `gorm:"uniqueIndex:idx_group_member;index" json:"device_id,omitempty"`
+}
+
+// Assignment of a file to a device group. A file without any assignment
+// is available tenant wide, otherwise only to the members of the groups.
+type FileGroupAssignment struct {
+	gorm.Model
+
+	FileID  uint `gorm:"uniqueIndex:idx_file_group" json:"-"`
+	GroupID uint `gorm:"uniqueIndex:idx_file_group;index" json:"group_id,omitempty"`
+
+	// The public id of the file, the primary key is never given out
+	FileUUID string `gorm:"-" json:"file_id,omitempty"`
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// newLinkToken is the short opaque token of the download link, url safe
+func newLinkToken() (string, error) {
+	var b [24]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return "", fmt.Errorf("Error generating token: %s", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// CreateDownloadLink - the link to the file valid for the number of uses until it expires
+func (r FileStoreRepositoryGORM) CreateDownloadLink(fid string, maxUses int64, expiresAt time.Time) ([]DownloadLink, int64, error) {
+	fidval, err := r.filePrimaryKey(fid)
+	if err != nil {
+		return nil, 0, err
+	}
+	if maxUses <= 0 {
+		return nil, 0, fmt.Errorf("Invalid max uses: %d", maxUses)
+	}
+	token, err := newLinkToken()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dl := DownloadLink{
+		Token:     token,
+		FileID:    fidval,
+		MaxUses:   maxUses,
+		ExpiresAt: expiresAt,
+	}
+	result := r.gormdb.Create(&dl)
+	dls := make([]DownloadLink, 1)
+	dls[0] = dl
+
+	return dls, result.RowsAffected, result.Error
+}
+
+// ReadDownloadLinks - all links of the file not revoked
+func (r FileStoreRepositoryGORM) ReadDownloadLinks(fid string) ([]DownloadLink, int64, error) {
+	fidval, err := r.filePrimaryKey(fid)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var dls []DownloadLink
+	result := r.gormdb.Where("file_id = ?", fidval).Find(&dls)
+
+	return dls, result.RowsAffected, result.Error
+}
+
+// ReadDownloadLink - the link by its token, used or not
+func (r FileStoreRepositoryGORM) ReadDownloadLink(token string) ([]DownloadLink, int64, error) {
+	var dls []DownloadLink
+	result := r.gormdb.Where("token = ?", token).Find(&dls)
+
+	return dls, result.RowsAffected, result.Error
+}
+
+// UseDownloadLink - counts the use of the link if it has any left and it has
+// not expired. The check and the increment are done in a single statement
+// so that the concurrent uses cannot exceed the limit, no row affected
+// means the use is refused.
+func (r FileStoreRepositoryGORM) UseDownloadLink(token string) (int64, error) {
+	now := time.Now()
+	result := r.gormdb.Model(&DownloadLink{}).
+		Where("token = ? AND uses < max_uses AND expires_at > ?", token, now).
+		Updates(map[string]interface{}{
+			"uses":         gorm.Expr("uses + 1"),
+			"last_used_at": now,
+		})
+
+	return result.RowsAffected, result.Error
+}
+
+// DeleteDownloadLink - revokes the link of the file
+func (r FileStoreRepositoryGORM) DeleteDownloadLink(fid, token string) (int64, error) {
+	fidval, err := r.filePrimaryKey(fid)
+	if err != nil {
+		return 0, err
+	}
+
+	result := r.gormdb.Where("file_id = ? AND token = ?", fidval, token).Delete(&DownloadLink{})
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Download link of a file served by the file service itself. The short opaque
+// token is redeemed for a freshly presigned GET at most the max uses times
+// until the link expires. The url is the path of the link on the service.
+type DownloadLink struct {
+	gorm.Model
+
+	Token      string     `gorm:"uniqueIndex" json:"token,omitempty"`
+	FileID     uint       `gorm:"index" json:"-"`
+	MaxUses    int64      `json:"max_uses"`
+	Uses       int64      `json:"uses"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	URL        string     `gorm:"-" json:"url,omitempty"`
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"gorm.io/gorm"
+)
+
+// CreateBatch - new records of the files of the device in one transaction,
+// each the next revision of its name. With dedup the file with the content
+// already uploaded by the tenant refers to its object and it is marked
+// deduplicated. Either all records are created or none.
+func (r FileStoreRepositoryGORM) CreateBatch(tid, did string, files []FileStore, dedup bool) ([]FileStore, int64, error) {
+	fas := make([]FileStore, 0, len(files))
+	err := r.gormdb.Transaction(func(tx *gorm.DB) error {
+		t := r.withDB(tx)
+		for _, f := range files {
+			if dedup && f.CheckSum != "" {
+				refs, count, err := t.CreateReference(tid, did, f.Name, f.CheckSumType, f.CheckSum, f.Size, f.ContentType, f.Metadata)
+				if err != nil {
+					return err
+				}
+				if count > 0 {
+					refs[0].Deduplicated = true
+					fas = append(fas, refs[0])
+					continue
+				}
+			}
+
+			created, _, err := t.Create(tid, did, f.Name, f.CheckSumType, f.CheckSum, f.Size, f.ContentType, f.Metadata)
+			if err != nil {
+				return err
+			}
+			fas = append(fas, created[0])
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return fas, int64(len(fas)), nil
+}
+
+// ReadByIds - the records by the public UUIDs in no particular order, the
+// ones not found are missing
+func (r FileStoreRepositoryGORM) ReadByIds(ids []string) ([]FileStore, int64, error) {
+	var fas []FileStore
+	result := r.gormdb.Where("uuid IN ?", ids).Find(&fas)
+
+	return fas, result.RowsAffected, result.Error
+}
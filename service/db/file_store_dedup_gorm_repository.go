@@ -0,0 +1,118 @@
+package db
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	. "fs/service/config"
+)
+
+// CreateReference - new record of the file sharing the completed object of
+// the same tenant with the same checksum and size if there is one. Only the
+// object whose content was verified against the base64 checksum is shared.
+// The object is locked so that it is not removed before the reference is
+// recorded. The record is not created if there is no such object.
+func (r FileStoreRepositoryGORM) CreateReference(tid, did, name, cksumType, cksum string, size int64, contentType string, metadata Metadata) ([]FileStore, int64, error) {
+	if cksumType == "" {
+		cksumType = Setup.CheckSumType
+	}
+	if cksum == "" {
+		return nil, 0, nil
+	}
+
+	var fas []FileStore
+	var rows int64
+	err := r.gormdb.Transaction(func(tx *gorm.DB) error {
+		var src []FileStore
+		result := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+			Where("tenant_id = ? AND check_sum_type = ? AND verified_check_sum = ? AND size = ? AND status = ? AND bucket <> '' AND object <> ''",
+				tid, cksumType, cksum, size, "C").
+			Order("id").
+			Limit(1).
+			Find(&src)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		var err error
+		fas, rows, err = r.withDB(tx).Create(tid, did, name, cksumType, cksum, size, contentType, metadata)
+		if err != nil {
+			return err
+		}
+		result = tx.Model(&FileStore{}).Where("id = ?", fas[0].ID).Updates(FileStore{
+			Location:         src[0].Location,
+			Bucket:           src[0].Bucket,
+			Object:           src[0].Object,
+			Credentials:      src[0].Credentials,
+			KeyLayout:        src[0].KeyLayout,
+			Encryption:       src[0].Encryption,
+			EncryptionKeyID:  src[0].EncryptionKeyID,
+			VersionID:        src[0].VersionID,
+			VerifiedCheckSum: src[0].VerifiedCheckSum,
+			Status:           "C",
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		result = tx.First(&fas[0], fas[0].ID)
+
+		return result.Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return fas, rows, nil
+}
+
+// ReadObjectRefs - the records sharing the object of the file, the file included
+func (r FileStoreRepositoryGORM) ReadObjectRefs(fs FileStore) ([]FileStore, int64, error) {
+	var fas []FileStore
+	result := r.gormdb.
+		Where("location = ? AND bucket = ? AND object = ?", fs.Location, fs.Bucket, fs.Object).
+		Order("id").
+		Find(&fas)
+
+	return fas, result.RowsAffected, result.Error
+}
+
+// DeleteReference - removes the record of the file unless it is under legal
+// hold and counts the records still sharing its object, the object is to be
+// removed by the caller once there are none
+func (r FileStoreRepositoryGORM) DeleteReference(fs FileStore) (int64, int64, error) {
+	var deleted, refs int64
+	err := r.gormdb.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("NOT legal_hold").Delete(&FileStore{}, fs.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		result = tx.Model(&FileStore{}).
+			Where("location = ? AND bucket = ? AND object = ?", fs.Location, fs.Bucket, fs.Object).
+			Count(&refs)
+
+		return result.Error
+	})
+
+	return deleted, refs, err
+}
+
+// SetSharedObjectKey - the object shared by the records was moved to the new
+// key of the layout with the version of its own
+func (r FileStoreRepositoryGORM) SetSharedObjectKey(fs FileStore, object string, layout int, version string) error {
+	result := r.gormdb.Model(&FileStore{}).
+		Where("location = ? AND bucket = ? AND object = ?", fs.Location, fs.Bucket, fs.Object).
+		Updates(map[string]interface{}{
+			"object":     object,
+			"key_layout": layout,
+			"version_id": version,
+		})
+
+	return result.Error
+}
+
+// withDB is the copy of the repository using the given handle, ex. the transaction
+func (r FileStoreRepositoryGORM) withDB(gormdb *gorm.DB) FileStoreRepositoryGORM {
+	r.gormdb = gormdb
+	return r
+}
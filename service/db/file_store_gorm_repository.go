@@ -2,7 +2,6 @@ package db
 
 import (
 	"fmt"
-	"strconv"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -58,6 +57,23 @@ func NewRepository() (FileStoreRepositoryGORM, error) {
 	Log.Debug("Opened GORM on backend DB")
 	gormdb.AutoMigrate(&FileStore{})
 	Log.Debug("Migrated object FileStore")
+	gormdb.AutoMigrate(&DeviceGroup{}, &DeviceGroupMember{}, &FileGroupAssignment{})
+	Log.Debug("Migrated objects DeviceGroup, DeviceGroupMember, FileGroupAssignment")
+	gormdb.AutoMigrate(&TenantStore{})
+	Log.Debug("Migrated object TenantStore")
+	gormdb.AutoMigrate(&DownloadLink{})
+	Log.Debug("Migrated object DownloadLink")
+	gormdb.AutoMigrate(&Bundle{})
+	Log.Debug("Migrated object Bundle")
+	gormdb.AutoMigrate(&Job{})
+	Log.Debug("Migrated object Job")
+	gormdb.AutoMigrate(&RetentionRule{})
+	Log.Debug("Migrated object RetentionRule")
+	gormdb.AutoMigrate(&UsageSummary{}, &UsageRefresh{})
+	Log.Debug("Migrated objects UsageSummary, UsageRefresh")
+	gormdb.AutoMigrate(&AuditEvent{})
+	Log.Debug("Migrated object AuditEvent")
+	protectAuditEvents(gormdb)
 
 	// Store results for use
 	return FileStoreRepositoryGORM{
@@ -66,54 +82,99 @@ func NewRepository() (FileStoreRepositoryGORM, error) {
 	}, nil
 }
 
-// Create new record allocating new ID
-func (r FileStoreRepositoryGORM) Create(tid, did, name, cksum string, size int64) ([]FileStore, int64, error) {
+// Create new record allocating new ID and the public UUID, the checksum type
+// defaults to the configured one. The record is the next revision of the file
+// of the same tenant, device and name, the revisions are numbered under the
+// lock of the name so that the concurrent uploads get distinct numbers.
+func (r FileStoreRepositoryGORM) Create(tid, did, name, cksumType, cksum string, size int64, contentType string, metadata Metadata) ([]FileStore, int64, error) {
+	if cksumType == "" {
+		cksumType = Setup.CheckSumType
+	}
+	uuid, err := NewUUID()
+	if err != nil {
+		return nil, 0, err
+	}
 	fa := FileStore{
+		UUID:         &uuid,
 		TenantID:     tid,
 		DeviceID:     did,
-		CheckSumType: Setup.CheckSumType,
+		CheckSumType: cksumType,
 		CheckSum:     cksum,
 		Name:         name,
 		Size:         size,
 		Status:       "N",
+		ContentType:  contentType,
+		Metadata:     metadata,
 	}
-	result := r.gormdb.Create(&fa)
+	var rows int64
+	err = r.gormdb.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", tid+"/"+did+"/"+name)
+		if result.Error != nil {
+			return result.Error
+		}
+		result = tx.Model(&FileStore{}).
+			Select("coalesce(max(revision), 0) + 1").
+			Where("tenant_id = ? AND device_id = ? AND name = ?", tid, did, name).
+			Scan(&fa.Revision)
+		if result.Error != nil {
+			return result.Error
+		}
+		result = tx.Create(&fa)
+		rows = result.RowsAffected
+		return result.Error
+	})
 	fas := make([]FileStore, 1)
 	fas[0] = fa
 
-	return fas, result.RowsAffected, result.Error
+	return fas, rows, err
 }
 
-// ReadById - primary key read by ID of the record
+// ReadById - unique key read by the public UUID of the record
 func (r FileStoreRepositoryGORM) ReadById(id string) ([]FileStore, int64, error) {
 	// Check the parameters
-	idpkval, err := strconv.ParseUint(id, 10, 64)
-	if err != nil {
-		return nil, 0, err
+	if !IsUUID(id) {
+		return nil, 0, fmt.Errorf("Invalid id: %s", id)
 	}
 
-	// Read the entity by primary key access
+	// Read the entity by unique key access
 	var fa FileStore
-	result := r.gormdb.First(&fa, idpkval)
+	result := r.gormdb.Where("uuid = ?", id).First(&fa)
 	fas := make([]FileStore, 1)
 	fas[0] = fa
 
 	return fas, result.RowsAffected, result.Error
 }
 
-// ReadByFilter - fiter by indexed (hopefully) attributes read
-func (r FileStoreRepositoryGORM) ReadByFilter(tid, did string) ([]FileStore, int64, error) {
+// ReadByFilter - fiter by indexed (hopefully) attributes read, the files
+// must have all the given metadata key/value pairs if any
+func (r FileStoreRepositoryGORM) ReadByFilter(tid, did string, metadata Metadata) ([]FileStore, int64, error) {
 	// Read the entity by fuilter on non-key attributes
 	var fas []FileStore
-	result := r.gormdb.Where("tenant_id = ? AND device_id = ?", tid, did).Find(&fas)
+	query := r.gormdb.Where("tenant_id = ? AND device_id = ?", tid, did)
+	if len(metadata) != 0 {
+		query = query.Where("metadata @> ?::jsonb", metadata)
+	}
+	result := query.Find(&fas)
+
+	return fas, result.RowsAffected, result.Error
+}
+
+// ReadByPrimaryKey - unique key read by the internal numeric ID of the record
+// as referred to by the other records
+func (r FileStoreRepositoryGORM) ReadByPrimaryKey(id uint) ([]FileStore, int64, error) {
+	var fa FileStore
+	result := r.gormdb.First(&fa, id)
+	fas := make([]FileStore, 1)
+	fas[0] = fa
 
 	return fas, result.RowsAffected, result.Error
 }
 
-// UpdateById - primary key update by ID of the record
-func (r FileStoreRepositoryGORM) UpdateById(id, status, cksum string, size int64) ([]FileStore, int64, error) {
+// UpdateById - update by the public UUID of the record, the checksum type
+// is kept unless given
+func (r FileStoreRepositoryGORM) UpdateById(id, status, cksumType, cksum string, size int64) ([]FileStore, int64, error) {
 	// Check the parameters
-	idpkval, err := strconv.ParseUint(id, 10, 64)
+	idpkval, err := r.filePrimaryKey(id)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -121,11 +182,13 @@ func (r FileStoreRepositoryGORM) UpdateById(id, status, cksum string, size int64
 		return nil, 0, fmt.Errorf("Invalid status, empty string")
 	}
 
-	// Do the GORM style update of selected parameters plus mandatory status
+	// Do the GORM style update of selected parameters plus mandatory status,
+	// the file under legal hold is left as it is
 	var fa FileStore
-	fa.ID = uint(idpkval)
-	result := r.gormdb.Model(&fa).Updates(FileStore{
-		CheckSumType: Setup.CheckSumType,
+	fa.ID = idpkval
+	fa.UUID = &id
+	result := r.gormdb.Model(&fa).Where("NOT legal_hold").Updates(FileStore{
+		CheckSumType: cksumType,
 		CheckSum:     cksum,
 		Size:         size,
 		Status:       status,
@@ -136,13 +199,163 @@ func (r FileStoreRepositoryGORM) UpdateById(id, status, cksum string, size int64
 	return fas, result.RowsAffected, result.Error
 }
 
-// SetBucketLocation
-func (r FileStoreRepositoryGORM) SetBucketLocation(id uint, bucket, location string) error {
+// SetLegalHold - sets or clears the legal hold of the file by the public UUID
+// with the reason of the hold
+func (r FileStoreRepositoryGORM) SetLegalHold(id string, hold bool, reason string) ([]FileStore, int64, error) {
+	idpkval, err := r.filePrimaryKey(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !hold {
+		reason = ""
+	}
+
+	result := r.gormdb.Model(&FileStore{}).Where("id = ?", idpkval).Updates(map[string]interface{}{
+		"legal_hold":        hold,
+		"legal_hold_reason": reason,
+	})
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	return r.ReadById(id)
+}
+
+// ReadRevisions - the revisions of the logical file of the given one, the same
+// tenant, device and name, the latest first
+func (r FileStoreRepositoryGORM) ReadRevisions(id string) ([]FileStore, int64, error) {
+	fss, count, err := r.ReadById(id)
+	if count == 0 || err != nil {
+		return nil, 0, err
+	}
+
+	var fas []FileStore
+	result := r.gormdb.
+		Where("tenant_id = ? AND device_id = ? AND name = ?", fss[0].TenantID, fss[0].DeviceID, fss[0].Name).
+		Order("revision DESC, id DESC").
+		Find(&fas)
+
+	return fas, result.RowsAffected, result.Error
+}
+
+// ReadRevision - the revision of the logical file of the given one by its number
+func (r FileStoreRepositoryGORM) ReadRevision(id string, revision int) ([]FileStore, int64, error) {
+	fss, count, err := r.ReadById(id)
+	if count == 0 || err != nil {
+		return nil, 0, err
+	}
+
+	var fas []FileStore
+	result := r.gormdb.
+		Where("tenant_id = ? AND device_id = ? AND name = ? AND revision = ?", fss[0].TenantID, fss[0].DeviceID, fss[0].Name, revision).
+		Order("id DESC").
+		Limit(1).
+		Find(&fas)
+
+	return fas, result.RowsAffected, result.Error
+}
+
+// SetVersionID records the version of the object assigned by the versioned bucket
+func (r FileStoreRepositoryGORM) SetVersionID(id uint, version string) error {
+	var fa FileStore
+	fa.ID = id
+	result := r.gormdb.Model(&fa).Updates(FileStore{
+		VersionID: version,
+	})
+
+	return result.Error
+}
+
+// filePrimaryKey - the internal numeric ID of the record by its public UUID
+func (r FileStoreRepositoryGORM) filePrimaryKey(id string) (uint, error) {
+	if !IsUUID(id) {
+		return 0, fmt.Errorf("Invalid id: %s", id)
+	}
+
+	var fa FileStore
+	result := r.gormdb.Select("id").Where("uuid = ?", id).First(&fa)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return fa.ID, nil
+}
+
+// SetBucketLocation records the store backend of the object with the reference
+// to the credentials used to reach it and the layout of its key
+func (r FileStoreRepositoryGORM) SetBucketLocation(id uint, bucket, location, object, credentials string, layout int) error {
+	var fa FileStore
+	fa.ID = id
+	result := r.gormdb.Model(&fa).Updates(FileStore{
+		Location:    location,
+		Bucket:      bucket,
+		Object:      object,
+		Credentials: credentials,
+		KeyLayout:   layout,
+	})
+
+	return result.Error
+}
+
+// SetEncryption records the server side encryption of the object
+func (r FileStoreRepositoryGORM) SetEncryption(id uint, mode, keyId string) error {
+	var fa FileStore
+	fa.ID = id
+	result := r.gormdb.Model(&fa).Updates(FileStore{
+		Encryption:      mode,
+		EncryptionKeyID: keyId,
+	})
+
+	return result.Error
+}
+
+// ReadByKeyLayout - a page of the files with the key of other layout then given,
+// ordered by ID starting after the given one
+func (r FileStoreRepositoryGORM) ReadByKeyLayout(layout int, after uint, limit int) ([]FileStore, int64, error) {
+	var fas []FileStore
+	result := r.gormdb.
+		Where("coalesce(key_layout, 0) <> ? and id > ?", layout, after).
+		Order("id").
+		Limit(limit).
+		Find(&fas)
+
+	return fas, result.RowsAffected, result.Error
+}
+
+// SetObjectKey - the object was moved to the new key of the layout
+func (r FileStoreRepositoryGORM) SetObjectKey(id uint, object string, layout int) error {
 	var fa FileStore
 	fa.ID = id
 	result := r.gormdb.Model(&fa).Updates(FileStore{
-		Location: location,
-		Bucket:   bucket,
+		Object:    object,
+		KeyLayout: layout,
+	})
+
+	return result.Error
+}
+
+// ReadByBucketObject - read by the location of the object in the store as
+// reported by the store events
+func (r FileStoreRepositoryGORM) ReadByBucketObject(bucket, object string) ([]FileStore, int64, error) {
+	var fas []FileStore
+	result := r.gormdb.Where("bucket = ? AND object = ?", bucket, object).Find(&fas)
+
+	return fas, result.RowsAffected, result.Error
+}
+
+// UpdateStatusById - sets the status after verification of the object with
+// the base64 checksum of the content found by it, the empty one if the
+// content was not verified
+func (r FileStoreRepositoryGORM) UpdateStatusById(id uint, status, verified string) error {
+	if status == "" {
+		return fmt.Errorf("Invalid status, empty string")
+	}
+
+	var fa FileStore
+	fa.ID = id
+	result := r.gormdb.Model(&fa).Updates(map[string]interface{}{
+		"status":             status,
+		"verified_check_sum": verified,
 	})
 
 	return result.Error
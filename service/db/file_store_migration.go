@@ -0,0 +1,17 @@
+package db
+
+import (
+	"gorm.io/gorm"
+)
+
+// BackfillFileUUIDs assigns the public ids to the rows created before they
+// were introduced, the files without one are not reachable by the api. It is
+// run once after the upgrade by the backfill-uuids command, by one statement
+// so that it either completes or leaves the rows to be backfilled again.
+func (r FileStoreRepositoryGORM) BackfillFileUUIDs() (int64, error) {
+	result := r.gormdb.Unscoped().Model(&FileStore{}).
+		Where("uuid IS NULL").
+		Update("uuid", gorm.Expr("gen_random_uuid()"))
+
+	return result.RowsAffected, result.Error
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -8,16 +10,81 @@ import (
 // The status state transition is: (W)aiting -> (U)ploaded, (D)ownloaded, (E)xpired
 // It is done with PATCH so file size or cksum may be changed as well in transit.
 type FileStore struct {
-	gorm.Model
+	// The numeric ID is internal, the files are identified in the API by the
+	// public UUID so that they can not be enumerated
+	ID        uint    `gorm:"primarykey" json:"-"`
+	UUID      *string `gorm:"type:uuid;uniqueIndex" json:"id,omitempty"`
+	CreatedAt time.Time
+	UpdatedAt time.Time      `gorm:"index"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 
-	Location     string `json:"location,omitempty"`
-	Bucket       string `json:"bucket,omitempty"`
-	TenantID     string `gorm:"index:idx_tenant_device" json:"tenent_id,omitempty"`
-	DeviceID     string `gorm:"index:idx_tenant_device" json:"device_id,omitempty"`
-	Name         string `json:"name,omitempty"`
+	Location    string `json:"location,omitempty"`
+	Bucket      string `json:"bucket,omitempty"`
+	Credentials string `json:"-"`
+	// The files of the tenant with the same content may share the object, it
+	// is removed with the last file referring to it
+	Object          string `gorm:"index" json:"object,omitempty"`
+	KeyLayout       int    `json:"key_layout,omitempty"`
+	Encryption      string `json:"encryption,omitempty"`
+	EncryptionKeyID string `json:"encryption_key_id,omitempty"`
+	TenantID        string `gorm:"index:idx_tenant_device" json:"tenent_id,omitempty"`
+	DeviceID        string `gorm:"index:idx_tenant_device" json:"device_id,omitempty"`
+	Name            string `json:"name,omitempty"`
+	// Each upload of the same tenant, device and name is the next revision of
+	// the logical file with the object of its own
+	Revision int `json:"revision,omitempty"`
+	// The version of the object the file is pinned to where the bucket is
+	// versioned
+	VersionID    string `json:"version_id,omitempty"`
 	CheckSumType string `json:"check_sum_type,omitempty"`
 	CheckSum     string `json:"check_sum,omitempty"`
-	Size         int64  `json:"size,omitempty"`
-	Status       string `json:"status,omitempty"`
-	URL          string `gorm:"-" json:"url,omitempty"`
+	// The base64 checksum of the content verified by the store or by the
+	// service, the empty one if the content was not verified
+	VerifiedCheckSum string `gorm:"index" json:"-"`
+	Size             int64  `json:"size,omitempty"`
+	// The checksum verified after upload leads to (C)reated or (X) for
+	// mismatch. The multipart upload goes: (N)ew -> (M)ultipart in progress
+	// -> (U)ploaded with no checksum verified, or back to (N)ew when aborted.
+	Status      string   `json:"status,omitempty"`
+	ContentType string   `json:"content_type,omitempty"`
+	Metadata    Metadata `gorm:"index:,type:gin" json:"metadata,omitempty"`
+	UploadID    string   `json:"upload_id,omitempty"`
+	PartSize    int64    `json:"part_size,omitempty"`
+	PartCount   int64    `json:"part_count,omitempty"`
+	// The parts received by the store are counted by the status read of the
+	// upload only, the progress is not kept in the db
+	PartsUploaded int64 `gorm:"-" json:"parts_uploaded,omitempty"`
+	// The file under legal hold must not be deleted by any means
+	LegalHold       bool   `gorm:"not null;default:false" json:"legal_hold,omitempty"`
+	LegalHoldReason string `json:"legal_hold_reason,omitempty"`
+	Deduplicated    bool   `gorm:"-" json:"deduplicated,omitempty"`
+	URL             string `gorm:"-" json:"url,omitempty"`
+	// The presigned url comes with the headers the client must send with it,
+	// ex. the key of server side encryption or the metadata of the object
+	Access *FileAccess `gorm:"-" json:"access,omitempty"`
+	Parts  []FilePart  `gorm:"-" json:"parts,omitempty"`
+}
+
+// Part of the file object uploaded with multipart upload. The url is
+// the presigned PUT for the part to be sent as described by the access,
+// the etag is returned by the store on the part upload and it is needed
+// to complete the upload.
+type FilePart struct {
+	Number int64       `json:"part_number"`
+	Size   int64       `json:"size,omitempty"`
+	ETag   string      `json:"etag,omitempty"`
+	URL    string      `json:"url,omitempty"`
+	Access *FileAccess `json:"access,omitempty"`
+}
+
+// Access to the file object by the presigned url. The request must be sent
+// with the method and the headers signed into the url before it expires,
+// the form fields are given for the POST policy upload. The url is repeated
+// in the file and the part for the clients reading it from there.
+type FileAccess struct {
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
 }
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"fmt"
+)
+
+// ReserveMultipartUpload takes the file object for the multipart upload about
+// to start unless it is already in progress. It returns 0 when the upload was
+// started by the concurrent request in the meantime.
+func (r FileStoreRepositoryGORM) ReserveMultipartUpload(id uint) (int64, error) {
+	result := r.gormdb.Model(&FileStore{}).
+		Where("id = ? AND status <> ?", id, "M").
+		Update("status", "M")
+
+	return result.RowsAffected, result.Error
+}
+
+// ReleaseMultipartUpload gives the status back to the file object reserved
+// for the upload which did not start
+func (r FileStoreRepositoryGORM) ReleaseMultipartUpload(id uint, status string) error {
+	result := r.gormdb.Model(&FileStore{}).
+		Where("id = ? AND status = ? AND upload_id = ?", id, "M", "").
+		Update("status", status)
+
+	return result.Error
+}
+
+// SetMultipartUpload registers the multipart upload started in the store
+// for the file object
+func (r FileStoreRepositoryGORM) SetMultipartUpload(id uint, uploadId string, partSize, partCount int64) error {
+	if uploadId == "" {
+		return fmt.Errorf("Invalid upload id, empty string")
+	}
+
+	var fa FileStore
+	fa.ID = id
+	result := r.gormdb.Model(&fa).Updates(map[string]interface{}{
+		"upload_id":  uploadId,
+		"part_size":  partSize,
+		"part_count": partCount,
+		"status":     "M",
+	})
+
+	return result.Error
+}
+
+// CompleteMultipartUpload marks the file object assembled from all parts as
+// uploaded, its content is not verified against the checksum
+func (r FileStoreRepositoryGORM) CompleteMultipartUpload(id uint) error {
+	var fa FileStore
+	fa.ID = id
+	result := r.gormdb.Model(&fa).Updates(map[string]interface{}{
+		"upload_id":          "",
+		"status":             "U",
+		"verified_check_sum": "",
+	})
+
+	return result.Error
+}
+
+// AbortMultipartUpload resets the file object to new as no data is kept
+func (r FileStoreRepositoryGORM) AbortMultipartUpload(id uint) error {
+	var fa FileStore
+	fa.ID = id
+	result := r.gormdb.Model(&fa).Updates(map[string]interface{}{
+		"upload_id":          "",
+		"part_size":          0,
+		"part_count":         0,
+		"status":             "N",
+		"verified_check_sum": "",
+	})
+
+	return result.Error
+}
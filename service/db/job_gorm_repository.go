@@ -0,0 +1,183 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateJob - the pending job of the type to be run as soon as possible
+func (r FileStoreRepositoryGORM) CreateJob(jobType, tid string, payload JobPayload, maxAttempts int) ([]Job, int64, error) {
+	if maxAttempts <= 0 {
+		return nil, 0, fmt.Errorf("Invalid max attempts: %d", maxAttempts)
+	}
+	uuid, err := NewUUID()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	j := Job{
+		UUID:        &uuid,
+		Type:        jobType,
+		TenantID:    tid,
+		Payload:     payload,
+		State:       "P",
+		RunAt:       time.Now(),
+		MaxAttempts: maxAttempts,
+	}
+	result := r.gormdb.Create(&j)
+	js := make([]Job, 1)
+	js[0] = j
+
+	return js, result.RowsAffected, result.Error
+}
+
+// ReadJobById - unique key read by the public UUID of the job
+func (r FileStoreRepositoryGORM) ReadJobById(id string) ([]Job, int64, error) {
+	if !IsUUID(id) {
+		return nil, 0, fmt.Errorf("Invalid id: %s", id)
+	}
+
+	var js []Job
+	result := r.gormdb.Where("uuid = ?", id).Find(&js)
+
+	return js, result.RowsAffected, result.Error
+}
+
+// ReadJobs - the latest jobs matching the type, the tenant and the state if given
+func (r FileStoreRepositoryGORM) ReadJobs(jobType, tid, state string, limit int) ([]Job, int64, error) {
+	query := r.gormdb
+	if jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+	if tid != "" {
+		query = query.Where("tenant_id = ?", tid)
+	}
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+
+	var js []Job
+	result := query.Order("id DESC").Limit(limit).Find(&js)
+
+	return js, result.RowsAffected, result.Error
+}
+
+// LeaseJob - takes the next job of the types due to run, or the running one
+// whose lease has expired, for the owner. The row is locked skipping the ones
+// locked by the other workers so that no two replicas run the same job. Each
+// lease is counted as the attempt.
+func (r FileStoreRepositoryGORM) LeaseJob(types []string, owner string, lease time.Duration) ([]Job, int64, error) {
+	var js []Job
+	err := r.gormdb.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("type IN ?", types).
+			Where("(state = ? AND run_at <= ?) OR (state = ? AND lease_expires_at < ?)", "P", now, "R", now).
+			Order("run_at").
+			Limit(1).
+			Find(&js)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		expires := now.Add(lease)
+		js[0].State = "R"
+		js[0].LeaseOwner = owner
+		js[0].LeaseExpiresAt = &expires
+		js[0].Attempts++
+		return tx.Model(&Job{}).Where("id = ?", js[0].ID).Updates(map[string]interface{}{
+			"state":            js[0].State,
+			"lease_owner":      js[0].LeaseOwner,
+			"lease_expires_at": js[0].LeaseExpiresAt,
+			"attempts":         js[0].Attempts,
+		}).Error
+	})
+
+	return js, int64(len(js)), err
+}
+
+// RenewJobLease - extends the lease of the running job held by the owner and
+// records its progress, no row affected means the lease was lost or the job
+// was cancelled and it must be stopped
+func (r FileStoreRepositoryGORM) RenewJobLease(id uint, owner string, lease time.Duration, progress, total int64) (int64, error) {
+	result := r.gormdb.Model(&Job{}).
+		Where("id = ? AND state = ? AND lease_owner = ?", id, "R", owner).
+		Updates(map[string]interface{}{
+			"lease_expires_at": time.Now().Add(lease),
+			"progress":         progress,
+			"total":            total,
+		})
+
+	return result.RowsAffected, result.Error
+}
+
+// CompleteJob - the job held by the owner is (D)one with the result
+func (r FileStoreRepositoryGORM) CompleteJob(id uint, owner string, progress, total int64, res JobPayload) (int64, error) {
+	result := r.gormdb.Model(&Job{}).
+		Where("id = ? AND state = ? AND lease_owner = ?", id, "R", owner).
+		Updates(map[string]interface{}{
+			"state":            "D",
+			"progress":         progress,
+			"total":            total,
+			"result":           res,
+			"error":            "",
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+			"finished_at":      time.Now(),
+		})
+
+	return result.RowsAffected, result.Error
+}
+
+// RetryJob - the failed job held by the owner goes back (P)ending to be run
+// again at the time
+func (r FileStoreRepositoryGORM) RetryJob(id uint, owner, message string, runAt time.Time) (int64, error) {
+	result := r.gormdb.Model(&Job{}).
+		Where("id = ? AND state = ? AND lease_owner = ?", id, "R", owner).
+		Updates(map[string]interface{}{
+			"state":            "P",
+			"run_at":           runAt,
+			"error":            message,
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		})
+
+	return result.RowsAffected, result.Error
+}
+
+// FailJob - the job held by the owner is (F)ailed for good
+func (r FileStoreRepositoryGORM) FailJob(id uint, owner, message string) (int64, error) {
+	result := r.gormdb.Model(&Job{}).
+		Where("id = ? AND state = ? AND lease_owner = ?", id, "R", owner).
+		Updates(map[string]interface{}{
+			"state":            "F",
+			"error":            message,
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+			"finished_at":      time.Now(),
+		})
+
+	return result.RowsAffected, result.Error
+}
+
+// CancelJob - the pending or running job is (C)ancelled, the worker running
+// it stops when it renews the lease
+func (r FileStoreRepositoryGORM) CancelJob(id string) (int64, error) {
+	if !IsUUID(id) {
+		return 0, fmt.Errorf("Invalid id: %s", id)
+	}
+
+	result := r.gormdb.Model(&Job{}).
+		Where("uuid = ? AND state IN ?", id, []string{"P", "R"}).
+		Updates(map[string]interface{}{
+			"state":            "C",
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+			"finished_at":      time.Now(),
+		})
+
+	return result.RowsAffected, result.Error
+}
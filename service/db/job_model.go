@@ -0,0 +1,37 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Job is the long running operation queued in the db to be run by any replica
+// of the service. The state goes: (P)ending -> (R)unning -> (D)one, the failed
+// run goes back to (P)ending to be retried at the run at time until the attempts
+// are exhausted and the job is (F)ailed. The pending or running job may be
+// (C)ancelled. The running job is leased by the worker for the limited time,
+// renewed while the job runs, so that the job of the worker which is gone is
+// taken over by another one once the lease expires.
+type Job struct {
+	ID        uint    `gorm:"primarykey" json:"-"`
+	UUID      *string `gorm:"type:uuid;uniqueIndex" json:"id,omitempty"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Type           string     `gorm:"index" json:"type"`
+	TenantID       string     `gorm:"index" json:"tenant_id,omitempty"`
+	Payload        JobPayload `json:"payload,omitempty"`
+	State          string     `gorm:"index:idx_job_state_run_at" json:"state"`
+	RunAt          time.Time  `gorm:"index:idx_job_state_run_at" json:"run_at"`
+	Attempts       int        `json:"attempts"`
+	MaxAttempts    int        `json:"max_attempts"`
+	LeaseOwner     string     `json:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	Progress       int64      `json:"progress"`
+	Total          int64      `json:"total"`
+	Result         JobPayload `json:"result,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
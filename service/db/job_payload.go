@@ -0,0 +1,60 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// JobPayload is the JSON document of the job type given on submit or
+// produced as the result. It is stored as JSONB and passed through as is.
+type JobPayload []byte
+
+// GormDataType is the type of the column
+func (JobPayload) GormDataType() string {
+	return "jsonb"
+}
+
+// Value is the JSON as given, the empty one is stored as NULL
+func (p JobPayload) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+
+	return string(p), nil
+}
+
+// Scan reads the JSON of the column
+func (p *JobPayload) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*p = nil
+	case []byte:
+		*p = append(JobPayload(nil), v...)
+	case string:
+		*p = JobPayload(v)
+	default:
+		return fmt.Errorf("Invalid job payload value type: %T", value)
+	}
+
+	return nil
+}
+
+// MarshalJSON embeds the document into the job
+func (p JobPayload) MarshalJSON() ([]byte, error) {
+	if len(p) == 0 {
+		return []byte("null"), nil
+	}
+
+	return p, nil
+}
+
+// UnmarshalJSON keeps the document of the request
+func (p *JobPayload) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*p = nil
+		return nil
+	}
+	*p = append(JobPayload(nil), b...)
+
+	return nil
+}
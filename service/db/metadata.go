@@ -0,0 +1,49 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Metadata is the key/value set attached to the file by the client, ex. log type
+// or firmware version. It is stored as JSONB so that the files can be filtered
+// by the containment of the given key/value pairs.
+type Metadata map[string]string
+
+// GormDataType is the type of the column
+func (Metadata) GormDataType() string {
+	return "jsonb"
+}
+
+// Value converts the metadata to JSON, the empty one is stored as NULL
+func (m Metadata) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Scan reads the metadata from JSON of the column
+func (m *Metadata) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("Invalid metadata value type: %T", value)
+	}
+
+	return json.Unmarshal(b, m)
+}
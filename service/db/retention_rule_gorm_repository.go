@@ -0,0 +1,107 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// ReadRetentionRules - the rules of the tenant
+func (r FileStoreRepositoryGORM) ReadRetentionRules(tid string) ([]RetentionRule, int64, error) {
+	var rrs []RetentionRule
+	result := r.gormdb.Where("tenant_id = ?", tid).Order("category").Find(&rrs)
+
+	return rrs, result.RowsAffected, result.Error
+}
+
+// ReadRetentionTenants - the tenants having any rule
+func (r FileStoreRepositoryGORM) ReadRetentionTenants() ([]string, error) {
+	var tids []string
+	result := r.gormdb.Model(&RetentionRule{}).Distinct().Order("tenant_id").Pluck("tenant_id", &tids)
+
+	return tids, result.Error
+}
+
+// UpsertRetentionRule - sets the rule of the tenant category replacing the previous one
+func (r FileStoreRepositoryGORM) UpsertRetentionRule(rr RetentionRule) ([]RetentionRule, int64, error) {
+	if rr.TenantID == "" {
+		return nil, 0, fmt.Errorf("Invalid retention rule, empty tenant")
+	}
+	if rr.MaxAgeDays < 0 || rr.MaxCount < 0 || rr.MaxBytes < 0 {
+		return nil, 0, fmt.Errorf("Invalid retention rule, negative limit")
+	}
+
+	result := r.gormdb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "category"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_age_days", "max_count", "max_bytes", "updated_at", "deleted_at"}),
+	}).Create(&rr)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	var rrs []RetentionRule
+	result = r.gormdb.Where("tenant_id = ? AND category = ?", rr.TenantID, rr.Category).Find(&rrs)
+
+	return rrs, result.RowsAffected, result.Error
+}
+
+// DeleteRetentionRule - removes the rule of the tenant category
+func (r FileStoreRepositoryGORM) DeleteRetentionRule(tid, category string) (int64, error) {
+	result := r.gormdb.Unscoped().Where("tenant_id = ? AND category = ?", tid, category).Delete(&RetentionRule{})
+
+	return result.RowsAffected, result.Error
+}
+
+// ReadRetentionCandidates - the uploaded files in the scope of the rule falling
+// outside any of its limits, the oldest first. The scope of the rule with no
+// category are the files of the categories not in the given ones, which are
+// the categories of the other rules of the tenant. The files under legal hold
+// count within the limits but they are never the candidates.
+func (r FileStoreRepositoryGORM) ReadRetentionCandidates(rr RetentionRule, others []string, limit int) ([]RetentionCandidate, int64, error) {
+	scope := r.gormdb.Model(&FileStore{}).
+		Select("*, "+
+			"row_number() OVER (PARTITION BY device_id ORDER BY created_at DESC, id DESC) AS device_rank, "+
+			"(sum(size) OVER (ORDER BY created_at DESC, id DESC))::bigint AS newer_bytes").
+		Where("tenant_id = ? AND status NOT IN ?", rr.TenantID, []string{"N", "M"})
+	if rr.Category != "" {
+		scope = scope.Where("metadata @> ?::jsonb", Metadata{"category": rr.Category})
+	} else if len(others) != 0 {
+		scope = scope.Where("(metadata->>'category' IS NULL OR metadata->>'category' NOT IN ?)", others)
+	}
+
+	var conds []string
+	var args []interface{}
+	args = append(args, scope)
+	if rr.MaxAgeDays > 0 {
+		conds = append(conds, "created_at < ?")
+		args = append(args, time.Now().Add(-rr.MaxAge()))
+	}
+	if rr.MaxCount > 0 {
+		conds = append(conds, "device_rank > ?")
+		args = append(args, rr.MaxCount)
+	}
+	if rr.MaxBytes > 0 {
+		conds = append(conds, "newer_bytes > ?")
+		args = append(args, rr.MaxBytes)
+	}
+	if len(conds) == 0 {
+		return nil, 0, nil
+	}
+	args = append(args, limit)
+
+	var rcs []RetentionCandidate
+	result := r.gormdb.Raw("SELECT * FROM (?) AS scope WHERE NOT legal_hold AND ("+strings.Join(conds, " OR ")+
+		") ORDER BY created_at, id LIMIT ?", args...).Scan(&rcs)
+
+	return rcs, result.RowsAffected, result.Error
+}
+
+// DeleteByPrimaryKey - removes the record of the file whose object was removed,
+// the file under legal hold is never removed
+func (r FileStoreRepositoryGORM) DeleteByPrimaryKey(id uint) (int64, error) {
+	result := r.gormdb.Where("NOT legal_hold").Delete(&FileStore{}, id)
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Retention rule of the tenant files of the metadata category, the rule with
+// no category applies to the files of the categories with no rule of their own.
+// The files older than the max age, the ones over the max count of the newest
+// files per device and the oldest ones over the max total bytes are removed.
+// The zero limit is not applied.
+type RetentionRule struct {
+	gorm.Model
+
+	TenantID   string `gorm:"uniqueIndex:idx_retention_tenant_category" json:"tenant_id,omitempty"`
+	Category   string `gorm:"uniqueIndex:idx_retention_tenant_category" json:"category,omitempty"`
+	MaxAgeDays int64  `json:"max_age_days,omitempty"`
+	MaxCount   int64  `json:"max_count,omitempty"`
+	MaxBytes   int64  `json:"max_bytes,omitempty"`
+}
+
+// Retention candidate is the file falling outside the rule with the rank of
+// the file among the newest ones of its device and the bytes of the files
+// newer than it in the scope of the rule
+type RetentionCandidate struct {
+	FileStore
+
+	DeviceRank int64 `json:"-"`
+	NewerBytes int64 `json:"-"`
+}
+
+// MaxAge is the max age of the files of the rule
+func (rr RetentionRule) MaxAge() time.Duration {
+	return time.Duration(rr.MaxAgeDays) * 24 * time.Hour
+}
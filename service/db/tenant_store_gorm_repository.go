@@ -0,0 +1,41 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm/clause"
+)
+
+// ReadTenantStore - the store backend mapped to the tenant if any
+func (r FileStoreRepositoryGORM) ReadTenantStore(tid string) ([]TenantStore, int64, error) {
+	var tss []TenantStore
+	result := r.gormdb.Where("tenant_id = ?", tid).Find(&tss)
+
+	return tss, result.RowsAffected, result.Error
+}
+
+// UpsertTenantStore - maps the tenant to the store backend replacing the previous one.
+// The files already stored keep the backend recorded with them.
+func (r FileStoreRepositoryGORM) UpsertTenantStore(ts TenantStore) ([]TenantStore, int64, error) {
+	if ts.TenantID == "" || ts.Kind == "" {
+		return nil, 0, fmt.Errorf("Invalid tenant store, empty tenant or kind")
+	}
+
+	result := r.gormdb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"kind", "bucket", "credentials_ref", "encryption", "kms_key_id", "presign_limits", "updated_at", "deleted_at"}),
+	}).Create(&ts)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	return r.ReadTenantStore(ts.TenantID)
+}
+
+// DeleteTenantStore - removes the mapping so the new files of the tenant go
+// to the default store
+func (r FileStoreRepositoryGORM) DeleteTenantStore(tid string) (int64, error) {
+	result := r.gormdb.Unscoped().Where("tenant_id = ?", tid).Delete(&TenantStore{})
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"gorm.io/gorm"
+)
+
+// Store backend dedicated to a tenant. The kind is the configured store,
+// ex. awss3 or awss3minio, the bucket is used as is without allocation
+// in the bucket space and the credentials reference is the prefix of env
+// variables with the keys overriding the ones of the kind. The encryption
+// overrides the one of the store kind for the new files of the tenant.
+// The presign limits override the configured ones by the method.
+type TenantStore struct {
+	gorm.Model
+
+	TenantID       string `gorm:"uniqueIndex" json:"tenant_id,omitempty"`
+	Kind           string `json:"kind,omitempty"`
+	Bucket         string `json:"bucket,omitempty"`
+	CredentialsRef string `json:"credentials_ref,omitempty"`
+	Encryption     string `json:"encryption,omitempty"`
+	KMSKeyID       string `json:"kms_key_id,omitempty"`
+	PresignLimits  string `json:"presign_limits,omitempty"`
+}
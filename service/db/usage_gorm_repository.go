@@ -0,0 +1,143 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// usageRefreshOverlap is the margin of the changes committed after the
+	// refresh started, they are taken again by the next one
+	usageRefreshOverlap = time.Minute
+
+	// usageRefreshID is the id of the only refresh record
+	usageRefreshID = 1
+)
+
+var (
+	// UsageDimensions are the columns the usage may be summed up by on top of the tenant
+	UsageDimensions = map[string]string{
+		"device": "device_id",
+		"month":  "month",
+		"status": "status",
+	}
+)
+
+// usageChangedGroups are the tenant device months with the files created,
+// updated or removed since the time given, and the ones with the files sharing
+// the object with them as the file counting its stored bytes may change
+const usageChangedGroups = `SELECT DISTINCT tenant_id, device_id, date_trunc('month', created_at)::date AS month
+	FROM file_stores WHERE updated_at > @since OR deleted_at > @since
+	OR (object <> '' AND (location, bucket, object) IN (SELECT location, bucket, object
+		FROM file_stores WHERE (updated_at > @since OR deleted_at > @since) AND object <> ''))`
+
+// usageSummaries sums up the files not removed by the tenant device month and
+// status. The object shared by the dedup is stored once, its size is counted
+// by the stored bytes of its first file only.
+const usageSummaries = `INSERT INTO usage_summaries (tenant_id, device_id, month, status, count, bytes, stored_bytes)
+	SELECT tenant_id, device_id, date_trunc('month', created_at)::date, status, count(*), coalesce(sum(size), 0),
+		coalesce(sum(CASE WHEN f.object = '' OR NOT EXISTS (SELECT 1 FROM file_stores o
+			WHERE o.deleted_at IS NULL AND o.location = f.location AND o.bucket = f.bucket
+			AND o.object = f.object AND o.id < f.id) THEN size ELSE 0 END), 0)
+	FROM file_stores f WHERE deleted_at IS NULL`
+
+// RefreshUsage - recomputes the summaries of the tenant device months with
+// the files changed since the last refresh, all of them if full or if never
+// refreshed. The concurrent refreshes are run one after the other. It returns
+// the number of the summaries written.
+func (r FileStoreRepositoryGORM) RefreshUsage(full bool) (int64, error) {
+	var rows int64
+	err := r.gormdb.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "usage_summaries")
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var started time.Time
+		result = tx.Raw("SELECT now()").Scan(&started)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var ur UsageRefresh
+		result = tx.Where("id = ?", usageRefreshID).Limit(1).Find(&ur)
+		if result.Error != nil {
+			return result.Error
+		}
+		full = full || result.RowsAffected == 0
+
+		if full {
+			result = tx.Exec("DELETE FROM usage_summaries")
+			if result.Error != nil {
+				return result.Error
+			}
+			result = tx.Exec(usageSummaries + `
+				GROUP BY 1, 2, 3, 4`)
+		} else {
+			since := sql.Named("since", ur.RefreshedAt)
+			result = tx.Exec(`DELETE FROM usage_summaries u USING (`+usageChangedGroups+`) g
+				WHERE u.tenant_id = g.tenant_id AND u.device_id = g.device_id AND u.month = g.month`, since)
+			if result.Error != nil {
+				return result.Error
+			}
+			result = tx.Exec(usageSummaries+`
+				AND (tenant_id, device_id, date_trunc('month', created_at)::date) IN (SELECT tenant_id, device_id, month FROM (`+usageChangedGroups+`) g)
+				GROUP BY 1, 2, 3, 4`, since)
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		rows = result.RowsAffected
+
+		return tx.Save(&UsageRefresh{ID: usageRefreshID, RefreshedAt: started.Add(-usageRefreshOverlap)}).Error
+	})
+
+	return rows, err
+}
+
+// ReadUsage - the usage of the tenant, all of them if not given, summed up by
+// the dimensions in the months from and to the given ones, the largest first.
+// The limit 0 means no limit.
+func (r FileStoreRepositoryGORM) ReadUsage(tid string, by []string, from, to *time.Time, limit int) ([]UsageRow, int64, error) {
+	columns := "tenant_id"
+	query := r.gormdb.Model(&UsageSummary{}).Group("tenant_id")
+	seen := make(map[string]bool)
+	for _, dim := range by {
+		column, ok := UsageDimensions[dim]
+		if !ok {
+			return nil, 0, fmt.Errorf("Invalid usage dimension: %s", dim)
+		}
+		if seen[column] {
+			continue
+		}
+		seen[column] = true
+		query = query.Group(column)
+		if column == "month" {
+			columns += ", to_char(month, 'YYYY-MM') AS month"
+		} else {
+			columns += ", " + column
+		}
+	}
+	query = query.Select(columns + ", sum(count)::bigint AS count, sum(bytes)::bigint AS bytes, sum(stored_bytes)::bigint AS stored_bytes")
+
+	if tid != "" {
+		query = query.Where("tenant_id = ?", tid)
+	}
+	if from != nil {
+		query = query.Where("month >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("month < ?", *to)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var urs []UsageRow
+	result := query.Order("bytes DESC, tenant_id").Scan(&urs)
+
+	return urs, int64(len(urs)), result.Error
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"time"
+)
+
+// Usage summary of the files of the tenant device created in the month by
+// their status: the number of the files, the total of their sizes and the
+// bytes stored. The summaries are refreshed from the files incrementally. The
+// files shared by the dedup are counted each with its size in the bytes, their
+// object is stored once and counted by the stored bytes of its first file.
+type UsageSummary struct {
+	TenantID    string    `gorm:"primaryKey" json:"tenant_id"`
+	DeviceID    string    `gorm:"primaryKey" json:"device_id"`
+	Month       time.Time `gorm:"primaryKey;type:date" json:"month"`
+	Status      string    `gorm:"primaryKey" json:"status"`
+	Count       int64     `json:"count"`
+	Bytes       int64     `json:"bytes"`
+	StoredBytes int64     `json:"stored_bytes"`
+}
+
+// Usage refresh is the time up to which the changes of the files are
+// reflected by the summaries, there is just one
+type UsageRefresh struct {
+	ID          uint `gorm:"primarykey"`
+	RefreshedAt time.Time
+}
+
+// Usage row of the report is the usage of the tenant summed up by the
+// dimensions asked for, the others are empty
+type UsageRow struct {
+	TenantID    string `json:"tenant_id"`
+	DeviceID    string `json:"device_id,omitempty"`
+	Month       string `json:"month,omitempty"`
+	Status      string `json:"status,omitempty"`
+	Count       int64  `json:"count"`
+	Bytes       int64  `json:"bytes"`
+	StoredBytes int64  `json:"stored_bytes"`
+}
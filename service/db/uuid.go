@@ -0,0 +1,29 @@
+package db
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+)
+
+var (
+	uuidPattern = regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$")
+)
+
+// NewUUID is the random (version 4) UUID in canonical text form
+func NewUUID() (string, error) {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return "", fmt.Errorf("Error generating uuid: %s", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// IsUUID checks the canonical text form of UUID
+func IsUUID(id string) bool {
+	return uuidPattern.MatchString(id)
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"os"
+
 	"fs/service/config"
 	"fs/service/rest"
+	"fs/service/store"
 )
 
 var (
@@ -10,12 +13,28 @@ var (
 )
 
 // main loads config, creates the servers and starts them if needed
+// unless the maintenance command is given to be run instead
 func main() {
 	config.Init(gitCommitHash, builtAt, builtBy, builtOn)
+
+	if len(config.Setup.CommandArgs) > 0 {
+		err := runCommand(config.Setup.CommandArgs)
+		if err != nil {
+			config.Log.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 	
 	server, err := rest.NewServer()
 	if err != nil {
 		panic(err)
 	}
+
+	// The jobs queued in the db are run by the workers of each replica
+	store.StartJobWorkers(config.Setup.JobWorkers)
+	if config.Setup.RetentionInterval > 0 {
+		store.StartRetentionScheduler(config.Setup.RetentionInterval)
+	}
 	server.RunServer()
 }
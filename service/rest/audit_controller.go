@@ -0,0 +1,122 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fs/service/db"
+)
+
+const (
+	// The number of the audit events of the page if not given and at most
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+)
+
+// Gets the audit events matching the parameters action, principal, tenant,
+// device, file and request_id if given, the latest first. The events are
+// limited to the times from and to (exclusive) in RFC 3339 if given, ex.
+// from=2024-01-01T00:00:00Z. The next page is the one before the id given
+// as next by the previous one.
+func ReadAuditEvents(w http.ResponseWriter, r *http.Request) {
+	af := db.AuditFilter{
+		Action:    r.FormValue("action"),
+		Principal: r.FormValue("principal"),
+		TenantID:  r.FormValue("tenant"),
+		DeviceID:  r.FormValue("device"),
+		FileID:    r.FormValue("file"),
+		RequestID: r.FormValue("request_id"),
+	}
+
+	var err error
+	af.From, err = auditTime(r, "from")
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+	af.To, err = auditTime(r, "to")
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
+	if val := r.FormValue("before"); val != "" {
+		before, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			displayAppError(w, UrlPathError,
+				"Invalid before - "+val,
+				http.StatusBadRequest)
+			return
+		}
+		af.Before = uint(before)
+	}
+
+	limit := auditDefaultLimit
+	if val := r.FormValue("limit"); val != "" {
+		limit, err = strconv.Atoi(val)
+		if err != nil || limit < 1 || limit > auditMaxLimit {
+			displayAppError(w, UrlPathError,
+				"Invalid limit - "+val+", expecting: 1 to "+strconv.Itoa(auditMaxLimit),
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	aes, count, err := dbrep.ReadAuditEvents(af, limit)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = AuditReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   aes,
+	}
+	if count == int64(limit) {
+		reply.Next = aes[count-1].ID
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// auditTime is the time of the parameter if given
+func auditTime(r *http.Request, label string) (*time.Time, error) {
+	val := r.FormValue(label)
+	if val == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s - %s, expecting: RFC 3339 time", label, val)
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,244 @@
+package rest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"fs/service/db"
+	"fs/service/store"
+)
+
+// auditAction is the action audited for the route with the method of the
+// presigned url if fixed by the route
+type auditAction struct {
+	Action string
+	Method string
+}
+
+// The audited routes by their names, the ones not listed are not audited
+var auditActions = map[string]auditAction{
+	"CreateFileStore":           {store.AuditActionCreate, "put"},
+	"CreateFileStoreBatch":      {store.AuditActionCreate, "put"},
+	"RollbackFileStoreRevision": {store.AuditActionCreate, ""},
+	"CreateBundle":              {store.AuditActionCreate, ""},
+
+	"ReadfileStoreAccessById":  {store.AuditActionPresign, ""},
+	"ReadFileStoreAccessBatch": {store.AuditActionPresign, ""},
+	"CreateFileStoreUpload":    {store.AuditActionPresign, "put"},
+	"ReadFileStoreUpload":      {store.AuditActionPresign, "put"},
+	"ReadFileStoreRevision":    {store.AuditActionPresign, "get"},
+	"ReadBundleById":           {store.AuditActionPresign, "get"},
+	"CreateDownloadLink":       {store.AuditActionPresign, "link"},
+	"RedirectDownloadLink":     {store.AuditActionPresign, "get"},
+
+	"UploadFileStoreContent":   {store.AuditActionAccess, "put"},
+	"DownloadFileStoreContent": {store.AuditActionAccess, "get"},
+
+	"UpdateFileStoreById":       {store.AuditActionUpdate, ""},
+	"VerifyFileStoreById":       {store.AuditActionUpdate, ""},
+	"CompleteFileStoreUpload":   {store.AuditActionUpdate, ""},
+	"DeleteFileStoreUpload":     {store.AuditActionUpdate, ""},
+	"DeleteDownloadLink":        {store.AuditActionUpdate, ""},
+	"CreateFileGroupAssignment": {store.AuditActionUpdate, ""},
+	"DeleteFileGroupAssignment": {store.AuditActionUpdate, ""},
+
+	"SetFileLegalHold":        {store.AuditActionAdmin, ""},
+	"ClearFileLegalHold":      {store.AuditActionAdmin, ""},
+	"UpdateRetentionRule":     {store.AuditActionAdmin, ""},
+	"DeleteRetentionRule":     {store.AuditActionAdmin, ""},
+	"EnforceRetentionRules":   {store.AuditActionAdmin, ""},
+	"UpdateTenantStore":       {store.AuditActionAdmin, ""},
+	"DeleteTenantStore":       {store.AuditActionAdmin, ""},
+	"RefreshUsage":            {store.AuditActionAdmin, ""},
+	"CreateJob":               {store.AuditActionAdmin, ""},
+	"CancelJob":               {store.AuditActionAdmin, ""},
+	"CreateDeviceGroup":       {store.AuditActionAdmin, ""},
+	"DeleteDeviceGroupById":   {store.AuditActionAdmin, ""},
+	"CreateDeviceGroupMember": {store.AuditActionAdmin, ""},
+	"DeleteDeviceGroupMember": {store.AuditActionAdmin, ""},
+}
+
+// The prefix of the principal read from the claims of the token whose
+// signature is not checked
+const auditPrincipalUnverified = "unverified:"
+
+type auditContextKey struct{}
+
+// auditRecord is the event of the request being handled completed by the
+// handler with the files acted on, one event is recorded per file
+type auditRecord struct {
+	event  db.AuditEvent
+	events []db.AuditEvent
+}
+
+// auditWriter keeps the status of the reply
+type auditWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (aw *auditWriter) WriteHeader(status int) {
+	aw.status = status
+	aw.ResponseWriter.WriteHeader(status)
+}
+
+func (aw *auditWriter) Flush() {
+	if f, ok := aw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// auditMiddleware records the audit event of the audited routes once handled
+func auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		aa, ok := auditActions[route.GetName()]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		vars := mux.Vars(r)
+		method := aa.Method
+		if method == "" {
+			method = vars["method"]
+		}
+		tenant := vars["tenant"]
+		if tenant == "" {
+			tenant = r.URL.Query().Get("tenant")
+		}
+		var file string
+		if strings.HasPrefix(r.URL.Path, "/api/v1/files/") || strings.HasPrefix(r.URL.Path, "/api/v1/admin/files/") {
+			file = vars["id"]
+		}
+		sourceIP, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			sourceIP = r.RemoteAddr
+		}
+
+		rec := &auditRecord{
+			event: db.AuditEvent{
+				Action:       aa.Action,
+				Method:       method,
+				Route:        route.GetName(),
+				Path:         r.URL.Path,
+				Principal:    tokenPrincipal(r),
+				TenantID:     tenant,
+				DeviceID:     r.URL.Query().Get("device"),
+				FileID:       file,
+				SourceIP:     sourceIP,
+				ForwardedFor: r.Header.Get("X-Forwarded-For"),
+				RequestID:    w.Header().Get("X-Request-Id"),
+			},
+		}
+		aw := &auditWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(aw, r.WithContext(context.WithValue(r.Context(), auditContextKey{}, rec)))
+
+		aes := rec.events
+		if len(aes) == 0 {
+			aes = []db.AuditEvent{rec.event}
+		}
+		for i := range aes {
+			aes[i].Status = aw.status
+		}
+		store.RecordAuditEvents(aes)
+	})
+}
+
+// auditFrom is the audit record of the request if audited
+func auditFrom(r *http.Request) *auditRecord {
+	rec, _ := r.Context().Value(auditContextKey{}).(*auditRecord)
+	return rec
+}
+
+// auditFile adds the file acted on by the request to its audit record
+func auditFile(r *http.Request, fs db.FileStore) {
+	rec := auditFrom(r)
+	if rec == nil {
+		return
+	}
+	ae := rec.event
+	ae.TenantID = fs.TenantID
+	ae.DeviceID = fs.DeviceID
+	if fs.UUID != nil {
+		ae.FileID = *fs.UUID
+	}
+	if len(rec.events) == 1 && rec.events[0].FileID == ae.FileID {
+		rec.events[0] = ae
+		return
+	}
+	rec.events = append(rec.events, ae)
+}
+
+// auditMethod sets the method of the presigned urls given by the payload
+func auditMethod(r *http.Request, method string) {
+	rec := auditFrom(r)
+	if rec == nil {
+		return
+	}
+	rec.event.Method = method
+	for i := range rec.events {
+		rec.events[i].Method = method
+	}
+}
+
+// auditDetail sets the detail of the action, ex. the reason of the legal hold
+func auditDetail(r *http.Request, detail string) {
+	rec := auditFrom(r)
+	if rec == nil {
+		return
+	}
+	rec.event.Detail = detail
+	for i := range rec.events {
+		rec.events[i].Detail = detail
+	}
+}
+
+// tokenPrincipal is the subject of the bearer token of the request. The auth
+// middleware does not validate the tokens yet, so the claims are taken as
+// given by the caller and the principal is marked unverified. The token other
+// than jwt is known by its short digest only, never by its value.
+func tokenPrincipal(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	if auth == "" || token == "" {
+		return "anonymous"
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) == 3 {
+		payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+		if err == nil {
+			var claims struct {
+				Subject  string `json:"sub"`
+				ClientID string `json:"client_id"`
+				Azp      string `json:"azp"`
+			}
+			if json.Unmarshal(payload, &claims) == nil {
+				switch {
+				case claims.Subject != "":
+					return auditPrincipalUnverified + claims.Subject
+				case claims.ClientID != "":
+					return auditPrincipalUnverified + claims.ClientID
+				case claims.Azp != "":
+					return auditPrincipalUnverified + claims.Azp
+				}
+			}
+		}
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:8])
+}
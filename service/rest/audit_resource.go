@@ -0,0 +1,16 @@
+package rest
+
+import (
+	"fs/service/db"
+)
+
+type (
+	// output: audit events, the latest first, with the cursor of the next
+	// page given as the before parameter if there may be more
+	AuditReplyResource struct {
+		Status bool            `json:"status"`
+		Count  int64           `json:"count"`
+		Next   uint            `json:"next,omitempty"`
+		Data   []db.AuditEvent `json:"data,omitempty"`
+	}
+)
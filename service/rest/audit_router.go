@@ -0,0 +1,16 @@
+package rest
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// NewAuditRouter creates the router for admin API of the audit log
+func NewAuditRouter(r *mux.Router) *mux.Router {
+	// Gets the audit events by the filter
+	r.HandleFunc("/api/v1/admin/audit",
+		ReadAuditEvents).
+		Methods("GET").
+		Name("ReadAuditEvents")
+
+	return r
+}
@@ -0,0 +1,227 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+// Starts the bundle of the tenant files matching the filter, ex. all logs of the
+// device of the day. The archive is produced by the job, the reply is the
+// pending bundle to be polled by its id.
+func CreateBundle(w http.ResponseWriter, r *http.Request) {
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	var request BundleRequestResource
+	err = json.Unmarshal(payload, &request)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to decode json payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+
+	if request.TenantID == "" {
+		displayAppError(w, PayloadReadError,
+			"Missing mandatory attribute tenant",
+			http.StatusBadRequest)
+		return
+	}
+	if request.Format == "" {
+		request.Format = store.BundleFormatZip
+	}
+	err = store.ValidateBundleFormat(request.Format)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+	if request.Status == "N" || request.Status == "M" {
+		displayAppError(w, PayloadReadError,
+			"Files of status "+request.Status+" are not uploaded and cannot be bundled",
+			http.StatusBadRequest)
+		return
+	}
+	if request.From != nil && request.To != nil && !request.From.Before(*request.To) {
+		displayAppError(w, PayloadReadError,
+			"Invalid time range, from must be before to",
+			http.StatusBadRequest)
+		return
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	bs, count, err := dbrep.CreateBundle(request.TenantID, request.DeviceID, request.Status, request.From, request.To, request.Format)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while creating entity - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	// The bundle outlives the request, it is run by any of the job workers
+	payload, err = json.Marshal(map[string]string{"bundle_id": *bs[0].UUID})
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	js, _, err := dbrep.CreateJob(store.JobTypeBundle, request.TenantID, payload, Setup.JobMaxAttempts)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while creating job - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	err = dbrep.SetBundleJob(bs[0].ID, *js[0].UUID)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while linking bundle to job - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	bs[0].JobID = *js[0].UUID
+	Log.Info("Created bundle " + *bs[0].UUID + " of tenant " + request.TenantID + " device " + request.DeviceID + " job " + *js[0].UUID)
+
+	var reply = BundleReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   bs,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusAccepted, jstr)
+}
+
+// Gets the bundle with its progress, the done one comes with the id of the
+// archive file and the presigned GET to download it
+func ReadBundleById(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	bs, count, err := dbrep.ReadBundleById(id)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no bundle found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if bs[0].State == store.BundleStateDone {
+		fss, count, err := dbrep.ReadByPrimaryKey(bs[0].FileID)
+		if count == 0 || err != nil {
+			displayAppError(w, RepositoryReadError,
+				"Error no file of bundle: "+id,
+				http.StatusNotFound)
+			return
+		}
+
+		auditFile(r, fss[0])
+		fsrep, err := store.NewRepository(store.FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+		if err != nil {
+			displayAppError(w, RepositoryNewError,
+				"Error while creating repository - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+
+		tss, _, err := dbrep.ReadTenantStore(fss[0].TenantID)
+		if err != nil {
+			displayAppError(w, RepositoryReadError,
+				"Error while reading tenant store - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		limits, err := store.TenantPresignLimits(tss)
+		if err != nil {
+			displayAppError(w, RepositoryReadError,
+				"Error while reading tenant store - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		expiry := store.PresignExpiry("get", 0, fsrep.PresignDuration(), limits)
+		access, err := fsrep.WithPresignDuration(expiry).FileObjectPresignedGetURL(fss[0].CheckSum, fss[0].Size,
+			store.ResponseOverrides{Disposition: store.DispositionAttachment})
+		if err != nil {
+			displayAppError(w, RepositoryUseError,
+				"Error while producing file object presigned url - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		bs[0].File = fss[0].UUID
+		bs[0].Access = &access
+		Log.Debug("Presigned GET of bundle " + id + fmt.Sprintf(" of %d bytes", bs[0].Size))
+	}
+
+	var reply = BundleReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   bs,
+	}
+
+	// Eascape chars shall not be replaces by unicodes as the standard MArshall does
+	var writer bytes.Buffer
+	enc := json.NewEncoder(&writer)
+	enc.SetEscapeHTML(false)
+	err = enc.Encode(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while encoding response data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	jstr := writer.Bytes()
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
@@ -0,0 +1,27 @@
+package rest
+
+import (
+	"time"
+
+	"fs/service/db"
+)
+
+type (
+	// input: for Create of the bundle of the tenant files, the device, the status
+	// and the time range of the file creation are optional, zip by default
+	BundleRequestResource struct {
+		TenantID string     `json:"tenant"`
+		DeviceID string     `json:"device,omitempty"`
+		Status   string     `json:"status,omitempty"`
+		From     *time.Time `json:"from,omitempty"`
+		To       *time.Time `json:"to,omitempty"`
+		Format   string     `json:"format,omitempty"`
+	}
+
+	// output: bundle with its progress and the access to the archive when done
+	BundleReplyResource struct {
+		Status bool        `json:"status"`
+		Count  int64       `json:"count"`
+		Data   []db.Bundle `json:"data,omitempty"`
+	}
+)
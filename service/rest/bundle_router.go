@@ -0,0 +1,23 @@
+package rest
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// NewBundleRouter creates the router for the bundles of the files
+// archived in the background
+func NewBundleRouter(r *mux.Router) *mux.Router {
+	// Starts the bundle of the files matching the filter
+	r.HandleFunc("/api/v1/bundles",
+		CreateBundle).
+		Methods("POST").
+		Name("CreateBundle")
+
+	// Gets the progress of the bundle and the access to the archive when done
+	r.HandleFunc("/api/v1/bundles/{id:[0-9a-f-]{36}}",
+		ReadBundleById).
+		Methods("GET").
+		Name("ReadBundleById")
+
+	return r
+}
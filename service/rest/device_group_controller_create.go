@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// Creates new named group of devices in the tenant
+func CreateDeviceGroup(w http.ResponseWriter, r *http.Request) {
+	tenant := r.FormValue("tenant")
+	if tenant == "" {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory parameter tenant",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got parameter tenant: " + tenant)
+
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	var request DeviceGroupRequestResource
+	err = json.Unmarshal(payload, &request)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to decode json payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+
+	if request.Name == "" {
+		displayAppError(w, PayloadReadError,
+			"Missing mandatory group name",
+			http.StatusBadRequest)
+		return
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	dgs, count, err := dbrep.CreateGroup(tenant, request.Name)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while creating entity - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = DeviceGroupReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   dgs,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// Deletes the device group by primary key together with its memberships
+// and file assignments
+func DeleteDeviceGroupById(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	count, err := dbrep.DeleteGroupById(id)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while deleting from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no group found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	var reply = DeleteReplyResource{
+		Status: true,
+		Count:  count,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
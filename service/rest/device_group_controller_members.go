@@ -0,0 +1,195 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// Adds a device to the device group
+func CreateDeviceGroupMember(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	var request DeviceGroupMemberRequestResource
+	err = json.Unmarshal(payload, &request)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to decode json payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+
+	if request.DeviceID == "" {
+		displayAppError(w, PayloadReadError,
+			"Missing mandatory device id",
+			http.StatusBadRequest)
+		return
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	_, count, _ := dbrep.ReadGroupById(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no group found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	dgms, count, err := dbrep.AddGroupMember(id, request.DeviceID)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while creating entity - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = DeviceGroupMemberReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   dgms,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Gets all devices being members of the device group
+func ReadDeviceGroupMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	dgms, count, err := dbrep.ReadGroupMembers(id)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = DeviceGroupMemberReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   dgms,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Removes a device from the device group
+func DeleteDeviceGroupMember(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	device, err := pathVariableStr(r, "device", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable device",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable device: " + device)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	count, err := dbrep.DeleteGroupMember(id, device)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while deleting from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no device "+device+" found in group: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	var reply = DeleteReplyResource{
+		Status: true,
+		Count:  count,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
@@ -0,0 +1,108 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// Gets all device groups of the tenant
+func ReadDeviceGroupByFilter(w http.ResponseWriter, r *http.Request) {
+	tenant := r.FormValue("tenant")
+	if tenant == "" {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory parameter tenant",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got parameter tenant: " + tenant)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	dgs, count, err := dbrep.ReadGroupsByTenant(tenant)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = DeviceGroupReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   dgs,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Gets existing device group by primary key
+func ReadDeviceGroupById(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	dgs, count, err := dbrep.ReadGroupById(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no group found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = DeviceGroupReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   dgs,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
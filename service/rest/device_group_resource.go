@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"fs/service/db"
+)
+
+type (
+	// input: for Create entity of device group
+	DeviceGroupRequestResource struct {
+		Name string `json:"name,omitempty"`
+	}
+
+	// input: for adding a device to the group
+	DeviceGroupMemberRequestResource struct {
+		DeviceID string `json:"device_id,omitempty"`
+	}
+
+	// input: for assigning a file to the group
+	FileGroupAssignmentRequestResource struct {
+		GroupID uint `json:"group_id,omitempty"`
+	}
+
+	// output: device groups
+	DeviceGroupReplyResource struct {
+		Status bool             `json:"status"`
+		Count  int64            `json:"count"`
+		Data   []db.DeviceGroup `json:"data,omitempty"`
+	}
+
+	// output: device group members
+	DeviceGroupMemberReplyResource struct {
+		Status bool                   `json:"status"`
+		Count  int64                  `json:"count"`
+		Data   []db.DeviceGroupMember `json:"data,omitempty"`
+	}
+
+	// output: file to group assignments
+	FileGroupAssignmentReplyResource struct {
+		Status bool                     `json:"status"`
+		Count  int64                    `json:"count"`
+		Data   []db.FileGroupAssignment `json:"data,omitempty"`
+	}
+
+	// output: count of deleted entities
+	DeleteReplyResource struct {
+		Status bool  `json:"status"`
+		Count  int64 `json:"count"`
+	}
+)
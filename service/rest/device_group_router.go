@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// NewDeviceGroupRouter creates the router for device group API used
+// for targeted distribution of the files to the devices of a tenant
+func NewDeviceGroupRouter(r *mux.Router) *mux.Router {
+	// Creates new named group of devices in the tenant
+	r.HandleFunc("/api/v1/groups",
+		CreateDeviceGroup).
+		Methods("POST").
+		Name("CreateDeviceGroup")
+
+	// Gets all groups of the tenant
+	r.HandleFunc("/api/v1/groups",
+		ReadDeviceGroupByFilter).
+		Methods("GET").
+		Name("ReadDeviceGroupByFilter")
+
+	// Gets existing group by numeric primary key
+	r.HandleFunc("/api/v1/groups/{id:[0-9]+}",
+		ReadDeviceGroupById).
+		Methods("GET").
+		Name("ReadDeviceGroupById")
+
+	// Deletes the group with its memberships and file assignments
+	r.HandleFunc("/api/v1/groups/{id:[0-9]+}",
+		DeleteDeviceGroupById).
+		Methods("DELETE").
+		Name("DeleteDeviceGroupById")
+
+	// Adds a device to the group
+	r.HandleFunc("/api/v1/groups/{id:[0-9]+}/devices",
+		CreateDeviceGroupMember).
+		Methods("POST").
+		Name("CreateDeviceGroupMember")
+
+	// Gets all devices of the group
+	r.HandleFunc("/api/v1/groups/{id:[0-9]+}/devices",
+		ReadDeviceGroupMembers).
+		Methods("GET").
+		Name("ReadDeviceGroupMembers")
+
+	// Removes a device from the group
+	r.HandleFunc("/api/v1/groups/{id:[0-9]+}/devices/{device}",
+		DeleteDeviceGroupMember).
+		Methods("DELETE").
+		Name("DeleteDeviceGroupMember")
+
+	// Assigns a file to the group limiting its availability to the group members
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/groups",
+		CreateFileGroupAssignment).
+		Methods("POST").
+		Name("CreateFileGroupAssignment")
+
+	// Gets all groups the file is assigned to
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/groups",
+		ReadFileGroupAssignments).
+		Methods("GET").
+		Name("ReadFileGroupAssignments")
+
+	// Removes the assignment of a file to the group
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/groups/{group:[0-9]+}",
+		DeleteFileGroupAssignment).
+		Methods("DELETE").
+		Name("DeleteFileGroupAssignment")
+
+	// Gets all files assigned to the groups the device is a member of
+	r.HandleFunc("/api/v1/files/available",
+		ReadFileStoreAvailable).
+		Methods("GET").
+		Name("ReadFileStoreAvailable")
+
+	return r
+}
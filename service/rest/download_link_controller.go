@@ -0,0 +1,355 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+// Creates the download link of the file usable at most max uses times, once
+// by default, until it expires. The link is created by the device the file is
+// available to. The link is served by the file service so the files encrypted
+// with the customer key, which need the key headers on GET, cannot be
+// downloaded by it.
+func CreateDownloadLink(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	// The payload is optional, the one time link valid for the configured time by default
+	var request DownloadLinkRequestResource
+	if len(payload) > 0 {
+		err = json.Unmarshal(payload, &request)
+		if err != nil {
+			displayAppError(w, PayloadReadError,
+				"Unable to decode json payload of the request",
+				http.StatusBadRequest)
+			return
+		}
+	}
+	if request.MaxUses < 0 || request.ExpiresIn < 0 {
+		displayAppError(w, PayloadReadError,
+			"Invalid max_uses or expires_in - "+fmt.Sprintf("%d, %d", request.MaxUses, request.ExpiresIn),
+			http.StatusBadRequest)
+		return
+	}
+	if request.MaxUses == 0 {
+		request.MaxUses = 1
+	}
+	ttl := time.Duration(request.ExpiresIn) * time.Second
+	if ttl == 0 || ttl > Setup.DownloadLinkTTL {
+		ttl = Setup.DownloadLinkTTL
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadById(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no item found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	auditFile(r, fss[0])
+
+	// The object must be in the store and readable by the plain redirect
+	if fss[0].Status == "N" || fss[0].Status == "M" {
+		displayAppError(w, RepositoryUseError,
+			"File "+id+" is not uploaded yet, status: "+fss[0].Status,
+			http.StatusConflict)
+		return
+	}
+	if fss[0].Encryption == store.EncryptionSSEC {
+		displayAppError(w, RepositoryUseError,
+			"File "+id+" encrypted with "+store.EncryptionSSEC+" cannot be downloaded by the link",
+			http.StatusConflict)
+		return
+	}
+
+	// The link is minted only by the device entitled to the file
+	if refuseUnavailableFile(w, dbrep, fss[0], r.FormValue("device")) {
+		return
+	}
+
+	dls, count, err := dbrep.CreateDownloadLink(id, request.MaxUses, time.Now().UTC().Add(ttl))
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while creating entity - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	dls[0].URL = "/d/" + dls[0].Token
+	Log.Info("Created download link " + linkTokenPrefix(dls[0].Token) + " of file " + id +
+		fmt.Sprintf(" for %d uses until %s", dls[0].MaxUses, dls[0].ExpiresAt.Format(time.RFC3339)))
+
+	var reply = DownloadLinkReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   dls,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusCreated, jstr)
+}
+
+// Gets the download links of the file with their uses
+func ReadDownloadLinks(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	dls, count, err := dbrep.ReadDownloadLinks(id)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	for i := range dls {
+		dls[i].URL = "/d/" + dls[i].Token
+	}
+
+	var reply = DownloadLinkReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   dls,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Revokes the download link of the file so that it cannot be used any more
+func DeleteDownloadLink(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	token, err := pathVariableStr(r, "token", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable token",
+			http.StatusBadRequest)
+		return
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	count, err := dbrep.DeleteDownloadLink(id, token)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while deleting from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no download link "+linkTokenPrefix(token)+" of file: "+id,
+			http.StatusNotFound)
+		return
+	}
+	Log.Info("Revoked download link " + linkTokenPrefix(token) + " of file " + id)
+
+	var reply = DeleteReplyResource{
+		Status: true,
+		Count:  count,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Counts the use of the download link and redirects to the freshly presigned
+// GET of the version of the file valid for the short configured time. The use
+// is counted before the url is produced so that the concurrent requests cannot
+// exceed the limit. Every use, allowed or refused, is logged.
+func RedirectDownloadLink(w http.ResponseWriter, r *http.Request) {
+	token, err := pathVariableStr(r, "token", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable token",
+			http.StatusBadRequest)
+		return
+	}
+	link := linkTokenPrefix(token)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	count, err := dbrep.UseDownloadLink(token)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while writing to db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	dls, found, err := dbrep.ReadDownloadLink(token)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	if found == 0 {
+		Log.Info("Download link " + link + " refused to " + r.RemoteAddr + ": unknown or revoked")
+		displayAppError(w, RepositoryReadError,
+			"Error no download link: "+link,
+			http.StatusNotFound)
+		return
+	}
+	uses := fmt.Sprintf("%d/%d", dls[0].Uses, dls[0].MaxUses)
+	if count == 0 {
+		Log.Info("Download link " + link + " refused to " + r.RemoteAddr + ": used " + uses +
+			" expires " + dls[0].ExpiresAt.Format(time.RFC3339))
+		displayAppError(w, AuthError,
+			"Download link "+link+" is used up or expired",
+			http.StatusGone)
+		return
+	}
+
+	fss, count, err := dbrep.ReadByPrimaryKey(dls[0].FileID)
+	if count == 0 || err != nil {
+		Log.Info("Download link " + link + " refused to " + r.RemoteAddr + ": file removed")
+		displayAppError(w, RepositoryReadError,
+			"Error no file of download link: "+link,
+			http.StatusNotFound)
+		return
+	}
+
+	auditFile(r, fss[0])
+	fsrep, err := store.NewRepository(store.FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	// The url is short lived within the limits of the tenant
+	tss, _, err := dbrep.ReadTenantStore(fss[0].TenantID)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading tenant store - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	limits, err := store.TenantPresignLimits(tss)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading tenant store - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	expiry := store.PresignExpiry("get", Setup.DownloadLinkPresign, fsrep.PresignDuration(), limits)
+	access, err := fsrep.WithPresignDuration(expiry).WithVersion(fss[0].VersionID).FileObjectPresignedGetURL(fss[0].CheckSum, fss[0].Size, store.ResponseOverrides{})
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while producing file object presigned url - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	Log.Info("Download link " + link + " of file " + fmt.Sprintf("%d", fss[0].ID) + " used " + uses + " by " + r.RemoteAddr)
+
+	// The presigned url must not be cached as the link may not be used again
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, access.URL, http.StatusFound)
+}
+
+// linkTokenPrefix identifies the link in the logs and errors not revealing the token
+func linkTokenPrefix(token string) string {
+	if len(token) <= 8 {
+		return token
+	}
+
+	return token[:8] + "..."
+}
@@ -0,0 +1,21 @@
+package rest
+
+import (
+	"fs/service/db"
+)
+
+type (
+	// input: for Create of the download link, one time use by default
+	// valid for the configured time unless given in seconds
+	DownloadLinkRequestResource struct {
+		MaxUses   int64 `json:"max_uses,omitempty"`
+		ExpiresIn int64 `json:"expires_in,omitempty"`
+	}
+
+	// output: download links of the file
+	DownloadLinkReplyResource struct {
+		Status bool              `json:"status"`
+		Count  int64             `json:"count"`
+		Data   []db.DownloadLink `json:"data,omitempty"`
+	}
+)
@@ -0,0 +1,36 @@
+package rest
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// NewDownloadLinkRouter creates the router for the download links served
+// by the file service redirecting to the store
+func NewDownloadLinkRouter(r *mux.Router) *mux.Router {
+	// Creates the link to the file usable the given number of times
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/links",
+		CreateDownloadLink).
+		Methods("POST").
+		Name("CreateDownloadLink")
+
+	// Gets the links of the file with their uses
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/links",
+		ReadDownloadLinks).
+		Methods("GET").
+		Name("ReadDownloadLinks")
+
+	// Revokes the link of the file
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/links/{token:[A-Za-z0-9_-]{32}}",
+		DeleteDownloadLink).
+		Methods("DELETE").
+		Name("DeleteDownloadLink")
+
+	// Counts the use of the link and redirects to the short lived
+	// presigned GET of the file
+	r.HandleFunc("/d/{token:[A-Za-z0-9_-]{32}}",
+		RedirectDownloadLink).
+		Methods("GET").
+		Name("RedirectDownloadLink")
+
+	return r
+}
@@ -0,0 +1,356 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+// Creates the records of many files of the device in one transaction with
+// the presigned PUT URL of each, ex. all the logs collected at boot. The item
+// failing validation or presigning is reported with its error, the others are
+// created nevertheless.
+func CreateFileStoreBatch(w http.ResponseWriter, r *http.Request) {
+	tenant := r.FormValue("tenant")
+	if tenant == "" {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory parameter tenant",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got paramter tenant: " + tenant)
+
+	device := r.FormValue("device")
+	if device == "" {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory parameter device",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got paramter device: " + device)
+
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	var request FileStoreBatchRequestResource
+	err = json.Unmarshal(payload, &request)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to decode json payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	if len(request.Files) == 0 || len(request.Files) > Setup.BatchMaxFiles {
+		displayAppError(w, PayloadReadError,
+			fmt.Sprintf("Invalid number of files %d, expected 1 to %d", len(request.Files), Setup.BatchMaxFiles),
+			http.StatusBadRequest)
+		return
+	}
+
+	// The invalid items are reported, the valid ones are created
+	items := make([]FileStoreBatchItemResource, len(request.Files))
+	var valid []int
+	var files []db.FileStore
+	for i := range request.Files {
+		items[i].Index = i
+		err = validateFileStoreRequest(&request.Files[i])
+		if err != nil {
+			items[i].Error = err.Error()
+			continue
+		}
+		valid = append(valid, i)
+		files = append(files, db.FileStore{
+			Name:         request.Files[i].Name,
+			CheckSumType: request.Files[i].CheckSumType,
+			CheckSum:     request.Files[i].CheckSum,
+			Size:         request.Files[i].Size,
+			ContentType:  request.Files[i].ContentType,
+			Metadata:     request.Files[i].Metadata,
+		})
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	var fss []db.FileStore
+	if len(files) > 0 {
+		fss, _, err = dbrep.CreateBatch(tenant, device, files, Setup.Dedup)
+		if err != nil {
+			displayAppError(w, RepositoryWriteError,
+				"Error while creating entities - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// The tenant may have the dedicated store backend instead of the default one
+	tss, _, err := dbrep.ReadTenantStore(tenant)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading tenant store - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	backend := store.TenantBackend(tss)
+	limits, err := store.TenantPresignLimits(tss)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading tenant store - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	for k, i := range valid {
+		fs := fss[k]
+		items[i].ID = *fs.UUID
+		if !fs.Deduplicated {
+			err = presignFileObjectPut(dbrep, &fs, backend, limits, request.Files[i].ExpiresIn)
+			if err != nil {
+				items[i].Error = err.Error()
+				continue
+			}
+		}
+		auditFile(r, fs)
+		items[i].File = &fs
+	}
+	Log.Info(fmt.Sprintf("Created %d of %d files of tenant %s device %s", len(fss), len(items), tenant, device))
+
+	writeFileStoreBatchReply(w, items)
+}
+
+// Gets the presigned GET or HEAD URL of each of the existing files by their
+// public ids. The item not found or not available to the device asking for
+// the download is reported with its error, the others are signed nevertheless.
+func ReadFileStoreAccessBatch(w http.ResponseWriter, r *http.Request) {
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	var request FileStoreAccessBatchRequestResource
+	err = json.Unmarshal(payload, &request)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to decode json payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	if request.Method != "get" && request.Method != "head" {
+		displayAppError(w, PayloadReadError,
+			"Invalid access method: "+request.Method+", expecting: get, head",
+			http.StatusBadRequest)
+		return
+	}
+	auditMethod(r, request.Method)
+	if len(request.IDs) == 0 || len(request.IDs) > Setup.BatchMaxFiles {
+		displayAppError(w, PayloadReadError,
+			fmt.Sprintf("Invalid number of ids %d, expected 1 to %d", len(request.IDs), Setup.BatchMaxFiles),
+			http.StatusBadRequest)
+		return
+	}
+	if request.ExpiresIn < 0 {
+		displayAppError(w, PayloadReadError,
+			"Invalid expires_in - "+fmt.Sprintf("%d", request.ExpiresIn),
+			http.StatusBadRequest)
+		return
+	}
+
+	// The device asking for the download must be entitled to each file
+	device := r.FormValue("device")
+	if request.Method == "get" && device == "" {
+		displayAppError(w, UrlPathError,
+			errDeviceMissing.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
+	items := make([]FileStoreBatchItemResource, len(request.IDs))
+	var ids []string
+	for i, id := range request.IDs {
+		items[i].Index = i
+		items[i].ID = id
+		if !db.IsUUID(id) {
+			items[i].Error = "Invalid id: " + id
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	var fss []db.FileStore
+	if len(ids) > 0 {
+		fss, _, err = dbrep.ReadByIds(ids)
+		if err != nil {
+			displayAppError(w, RepositoryReadError,
+				"Error while reading from db repository - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+	}
+	files := make(map[string]db.FileStore, len(fss))
+	for _, fs := range fss {
+		files[*fs.UUID] = fs
+	}
+
+	// The limits are the ones of the tenant owning the file
+	tenantLimits := make(map[string]PresignLimits)
+	for i := range items {
+		if items[i].Error != "" {
+			continue
+		}
+		fs, ok := files[items[i].ID]
+		if !ok {
+			items[i].Error = "No item found by id: " + items[i].ID
+			continue
+		}
+
+		if request.Method == "get" {
+			err = fileAvailable(dbrep, fs, device)
+			if err != nil {
+				items[i].Error = err.Error()
+				continue
+			}
+		}
+
+		limits, ok := tenantLimits[fs.TenantID]
+		if !ok {
+			tss, _, err := dbrep.ReadTenantStore(fs.TenantID)
+			if err == nil {
+				limits, err = store.TenantPresignLimits(tss)
+			}
+			if err != nil {
+				items[i].Error = "Error while reading tenant store - " + err.Error()
+				continue
+			}
+			tenantLimits[fs.TenantID] = limits
+		}
+
+		fsrep, err := store.NewRepository(store.FileBackend(fs), fs.TenantID, fs.DeviceID, fs.Name, fs.ID)
+		if err != nil {
+			items[i].Error = "Error while creating repository - " + err.Error()
+			continue
+		}
+		expiry := store.PresignExpiry(request.Method, time.Duration(request.ExpiresIn)*time.Second, fsrep.PresignDuration(), limits)
+		fsrep = fsrep.WithPresignDuration(expiry)
+
+		var access db.FileAccess
+		if request.Method == "head" {
+			access, err = fsrep.FileObjectPresignedHeadURL(fs.CheckSum, fs.Size)
+		} else {
+			access, err = fsrep.FileObjectPresignedGetURL(fs.CheckSum, fs.Size, store.ResponseOverrides{})
+		}
+		if err != nil {
+			items[i].Error = "Error while producing file object presigned url - " + err.Error()
+			continue
+		}
+		fs.URL = access.URL
+		fs.Access = &access
+		auditFile(r, fs)
+		items[i].File = &fs
+	}
+
+	writeFileStoreBatchReply(w, items)
+}
+
+// presignFileObjectPut allocates the object of the new file in the store of
+// the tenant and signs the PUT URL to upload it as the single create does
+func presignFileObjectPut(dbrep db.FileStoreRepositoryGORM, fs *db.FileStore, backend store.Backend, limits PresignLimits, expiresIn int64) error {
+	backend.UUID = *fs.UUID
+	fsrep, err := store.NewRepository(backend, fs.TenantID, fs.DeviceID, fs.Name, fs.ID)
+	if err != nil {
+		return fmt.Errorf("Error while creating repository - %s", err)
+	}
+	location, bucket := fsrep.BucketLocation()
+
+	err = fsrep.AssureBucketExist()
+	if err != nil {
+		return fmt.Errorf("Error while provisioning bucket %s - %s", bucket, err)
+	}
+	err = dbrep.SetBucketLocation(fs.ID, bucket, location, fsrep.ObjectName(), backend.Credentials, fsrep.ObjectKeyLayout())
+	if err != nil {
+		return fmt.Errorf("Error while linking object bucket to location of object - %s", err)
+	}
+	encryption, encryptionKeyID := fsrep.Encryption()
+	err = dbrep.SetEncryption(fs.ID, encryption, encryptionKeyID)
+	if err != nil {
+		return fmt.Errorf("Error while registering encryption of object - %s", err)
+	}
+
+	expiry := store.PresignExpiry("put", time.Duration(expiresIn)*time.Second, fsrep.PresignDuration(), limits)
+	access, err := fsrep.WithPresignDuration(expiry).FileObjectPresignedPutURL(fs.CheckSumType, fs.CheckSum, fs.Size, fs.ContentType, fs.Metadata)
+	if err != nil {
+		return fmt.Errorf("Error while allocating file object url - %s", err)
+	}
+
+	fs.Location = location
+	fs.Bucket = bucket
+	fs.Object = fsrep.ObjectName()
+	fs.KeyLayout = fsrep.ObjectKeyLayout()
+	fs.Encryption = encryption
+	fs.EncryptionKeyID = encryptionKeyID
+	fs.URL = access.URL
+	fs.Access = &access
+
+	return nil
+}
+
+// writeFileStoreBatchReply replies the items of the batch with the urls
+// not escaped
+func writeFileStoreBatchReply(w http.ResponseWriter, items []FileStoreBatchItemResource) {
+	var reply = FileStoreBatchReplyResource{
+		Status: true,
+		Count:  int64(len(items)),
+		Data:   items,
+	}
+	for _, item := range items {
+		if item.Error != "" {
+			reply.Failed++
+		}
+	}
+
+	// Eascape chars shall not be replaces by unicodes as the standard MArshall does
+	var writer bytes.Buffer
+	enc := json.NewEncoder(&writer)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while encoding response data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	jstr := writer.Bytes()
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
@@ -0,0 +1,251 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+// UploadFileStoreContent receives the content of the existing file store resource
+// in proxy mode and streams it to the store for the devices that cannot reach
+// the store directly. The checksum is calculated on the fly, the status becomes
+// (C)reated on match or (X) for checksum mismatch.
+func UploadFileStoreContent(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	// The length is signed into the url of the store so it must be known up front
+	if r.ContentLength < 0 {
+		displayAppError(w, PayloadReadError,
+			"Missing content length of the request",
+			http.StatusLengthRequired)
+		return
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadById(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no item found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if fss[0].Status == "M" {
+		displayAppError(w, RepositoryUseError,
+			"File "+id+" has multipart upload in progress",
+			http.StatusConflict)
+		return
+	}
+	if refuseLegalHold(w, fss[0]) || refuseSharedObject(w, dbrep, fss[0]) {
+		return
+	}
+	if fss[0].Size != 0 && fss[0].Size != r.ContentLength {
+		displayAppError(w, PayloadReadError,
+			"Content length "+fmt.Sprintf("%d", r.ContentLength)+" does not match file size "+fmt.Sprintf("%d", fss[0].Size),
+			http.StatusBadRequest)
+		return
+	}
+	size := r.ContentLength
+
+	auditFile(r, fss[0])
+	fsrep, err := store.NewRepository(store.FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	access, err := fsrep.FileObjectPresignedPutURL(fss[0].CheckSumType, fss[0].CheckSum, size, fss[0].ContentType, fss[0].Metadata)
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while producing file object presigned url - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	// The declared checksum is checked on the fly and by the store, the mismatch
+	// found by any of them marks the file
+	sum, err := store.ProxyUpload(access, r.Body, size, fss[0].CheckSumType, fss[0].CheckSum)
+	status := "C"
+	verified := ""
+	if fss[0].CheckSum != "" && sum != "" {
+		expected, _ := store.ChecksumBase64(fss[0].CheckSumType, fss[0].CheckSum)
+		verified = sum
+		if sum != expected {
+			verified = ""
+			Log.Error("Checksum mismatch of proxied content of file " + id +
+				": expected " + fss[0].CheckSumType + " " + expected + ", received " + sum)
+			status = "X"
+		}
+	}
+	if err != nil && status != "X" {
+		displayAppError(w, RepositoryUseError,
+			"Error while streaming file content to store - "+err.Error(),
+			http.StatusBadGateway)
+		return
+	}
+	Log.Debug("Proxied content of file " + id + fmt.Sprintf(" of %d bytes", size))
+
+	fid := fss[0].ID
+	_, _, err = dbrep.UpdateById(id, status, "", "", size)
+	if err == nil {
+		err = dbrep.UpdateStatusById(fid, status, verified)
+	}
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while updating status - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	if status == "X" {
+		displayAppError(w, PayloadReadError,
+			"Content of file "+id+" does not match the checksum",
+			http.StatusUnprocessableEntity)
+		return
+	}
+
+	fss, count, err = dbrep.ReadById(id)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = FileStoreReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   fss,
+	}
+
+	// Eascape chars shall not be replaces by unicodes as the standard MArshall does
+	var writer bytes.Buffer
+	enc := json.NewEncoder(&writer)
+	enc.SetEscapeHTML(false)
+	err = enc.Encode(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while encoding response data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	jstr := writer.Bytes()
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// DownloadFileStoreContent serves the content of the existing file store resource
+// in proxy mode streaming it from the store. The range and the conditional
+// requests are passed to the store so the partial content is served as well.
+func DownloadFileStoreContent(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadById(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no item found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if fss[0].Status == "N" || fss[0].Status == "M" {
+		displayAppError(w, RepositoryUseError,
+			"File "+id+" is not uploaded yet, status: "+fss[0].Status,
+			http.StatusConflict)
+		return
+	}
+
+	if refuseUnavailableFile(w, dbrep, fss[0], r.FormValue("device")) {
+		return
+	}
+
+	auditFile(r, fss[0])
+	fsrep, err := store.NewRepository(store.FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	access, err := fsrep.FileObjectPresignedGetURL(fss[0].CheckSum, fss[0].Size, store.ResponseOverrides{})
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while producing file object presigned url - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := store.ProxyDownload(access, r.Header)
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while streaming file content from store - "+err.Error(),
+			http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	// The content is streamed as it comes, the failure in the middle can only
+	// be logged as the headers are already sent
+	store.CopyProxyHeaders(w.Header(), resp)
+	w.WriteHeader(resp.StatusCode)
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		Log.Error("Error while streaming file content of " + id + " - " + err.Error())
+		return
+	}
+	Log.Debug("Proxied content of file " + id + fmt.Sprintf(" of %d bytes with status %d", n, resp.StatusCode))
+}
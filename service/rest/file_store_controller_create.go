@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	. "fs/service/config"
 	"fs/service/db"
@@ -50,6 +52,14 @@ func CreateFileStore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = validateFileStoreRequest(&request)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
 	dbrep, err := db.NewRepository()
 	if err != nil {
 		displayAppError(w, RepositoryNewError,
@@ -59,8 +69,43 @@ func CreateFileStore(w http.ResponseWriter, r *http.Request) {
 	}
 	defer dbrep.Close()
 
+	// The content already uploaded by the tenant is not uploaded again, the new
+	// file refers to the existing object and needs no url
+	if Setup.Dedup && request.CheckSum != "" {
+		fss, count, err := dbrep.CreateReference(tenant, device, request.Name, request.CheckSumType, request.CheckSum, request.Size, request.ContentType, request.Metadata)
+		if err != nil {
+			displayAppError(w, RepositoryWriteError,
+				"Error while creating entity - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		if count > 0 {
+			Log.Info("Created file " + *fss[0].UUID + " referring to existing object " + fss[0].Object)
+			fss[0].Deduplicated = true
+			auditFile(r, fss[0])
+
+			var reply = FileStoreReplyResource{
+				Status: true,
+				Count:  count,
+				Data:   fss,
+			}
+
+			jstr, err := json.Marshal(&reply)
+			if err != nil {
+				displayAppError(w, EncoderJsonError,
+					"An error while marshalling data - "+err.Error(),
+					http.StatusInternalServerError)
+				return
+			}
+			logReply(jstr)
+
+			writeResponseWithJson(w, http.StatusOK, jstr)
+			return
+		}
+	}
+
 	// The ID is allocated by a unique seq to be used as part of the name of the object
-	fss, count, err := dbrep.Create(tenant, device, request.Name, request.CheckSum, request.Size)
+	fss, count, err := dbrep.Create(tenant, device, request.Name, request.CheckSumType, request.CheckSum, request.Size, request.ContentType, request.Metadata)
 	if err != nil {
 		displayAppError(w, RepositoryWriteError,
 			"Error while creating entity - "+err.Error(),
@@ -70,8 +115,26 @@ func CreateFileStore(w http.ResponseWriter, r *http.Request) {
 	id := fss[0].ID
 	Log.Debug("Created db record with ID: " + fmt.Sprintf("%d", id))
 
+	// The tenant may have the dedicated store backend instead of the default one
+	tss, _, err := dbrep.ReadTenantStore(tenant)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading tenant store - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	backend := store.TenantBackend(tss)
+	backend.UUID = *fss[0].UUID
+	limits, err := store.TenantPresignLimits(tss)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading tenant store - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
 	// As the bucket name may be allocated on the fly in case of more then 1 buckets
-	fsrep, err := store.NewRepository(Setup.UseFileStore, tenant, device, request.Name, id)
+	fsrep, err := store.NewRepository(backend, tenant, device, request.Name, id)
 	if err != nil {
 		displayAppError(w, RepositoryNewError,
 			"Error while creating repository - "+err.Error(),
@@ -80,8 +143,17 @@ func CreateFileStore(w http.ResponseWriter, r *http.Request) {
 	}
 	location, bucket := fsrep.BucketLocation()
 
+	// The allocated bucket is provisioned on first use
+	err = fsrep.AssureBucketExist()
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while provisioning bucket "+bucket+" - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
 	// The initially created db entry must be updated and the bucket registered
-	err = dbrep.SetBucketLocation(id, bucket, location)
+	err = dbrep.SetBucketLocation(id, bucket, location, fsrep.ObjectName(), backend.Credentials, fsrep.ObjectKeyLayout())
 	if err != nil {
 		displayAppError(w, RepositoryWriteError,
 			"Error while linking object bucket to location of object - "+err.Error(),
@@ -89,20 +161,40 @@ func CreateFileStore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The encryption is needed to read the object later on
+	encryption, encryptionKeyID := fsrep.Encryption()
+	err = dbrep.SetEncryption(id, encryption, encryptionKeyID)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while registering encryption of object - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
 	// Now is the time to get a presigned URL pointing to an object to be created
-	// by PUT operation by the client
-	url, err := fsrep.FileObjectPresignedPutURL(request.CheckSum, request.Size)
+	// by PUT operation by the client within the validity it asked for
+	expiry := store.PresignExpiry("put", time.Duration(request.ExpiresIn)*time.Second, fsrep.PresignDuration(), limits)
+	fsrep = fsrep.WithPresignDuration(expiry)
+	access, err := fsrep.FileObjectPresignedPutURL(request.CheckSumType, request.CheckSum, request.Size, request.ContentType, request.Metadata)
 	if err != nil {
 		displayAppError(w, RepositoryUseError,
 			"Error while allocating file object url - "+err.Error(),
 			http.StatusInternalServerError)
 		return
 	}
-	Log.Debug("Created client PUT presigned url: " + url)
+	Log.Debug("Created client PUT presigned url: " + access.URL)
 
 	// The presigned url and seq of name/content form data is not stored in the DB
 	// but it must be returned to the client.
-	fss[0].URL = url
+	fss[0].Location = location
+	fss[0].Bucket = bucket
+	fss[0].Object = fsrep.ObjectName()
+	fss[0].KeyLayout = fsrep.ObjectKeyLayout()
+	fss[0].Encryption = encryption
+	fss[0].EncryptionKeyID = encryptionKeyID
+	fss[0].URL = access.URL
+	fss[0].Access = &access
+	auditFile(r, fss[0])
 	
 	var reply = FileStoreReplyResource{
 		Status: true,
@@ -122,7 +214,87 @@ func CreateFileStore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	jstr := writer.Bytes()
-	Log.Debug("Reply: " + string(jstr))
+	logReply(jstr)
 	
 	writeResponseWithJson(w, http.StatusOK, jstr)
 }
+
+// validateFileStoreRequest checks the file to be created, the checksum type
+// defaults to the configured one
+func validateFileStoreRequest(request *FileStoreRequestResource) error {
+	// The checksum must be well formed for its algorithm to be signed into the url
+	request.CheckSumType = strings.ToUpper(request.CheckSumType)
+	if request.CheckSumType == "" {
+		request.CheckSumType = Setup.CheckSumType
+	}
+	err := store.ValidateChecksumType(request.CheckSumType)
+	if err != nil {
+		return fmt.Errorf("Invalid checksum - %s", err)
+	}
+	// It is kept in base64 to be compared with the verified one
+	if request.CheckSum != "" {
+		sum, err := store.ChecksumBase64(request.CheckSumType, request.CheckSum)
+		if err != nil {
+			return fmt.Errorf("Invalid checksum - %s", err)
+		}
+		request.CheckSum = sum
+	}
+
+	// The content type is signed into the url so it must be an allowed one
+	if request.ContentType != "" {
+		err := store.ValidateContentType(request.ContentType)
+		if err != nil {
+			return fmt.Errorf("Invalid content type - %s", err)
+		}
+	}
+
+	// The validity of the url in seconds is clamped to the limits later on
+	if request.ExpiresIn < 0 {
+		return fmt.Errorf("Invalid expires_in - %d", request.ExpiresIn)
+	}
+
+	// The metadata goes to the store as the object metadata and tags
+	err = store.ValidateMetadata(request.Metadata)
+	if err != nil {
+		return fmt.Errorf("Invalid metadata - %s", err)
+	}
+
+	return nil
+}
+
+// refuseLegalHold replies the conflict if the file is under legal hold as the
+// new content, checksum or size would replace the held ones
+func refuseLegalHold(w http.ResponseWriter, fs db.FileStore) bool {
+	if !fs.LegalHold {
+		return false
+	}
+
+	displayAppError(w, RepositoryUseError,
+		"File "+*fs.UUID+" is under legal hold, it cannot be changed",
+		http.StatusConflict)
+	return true
+}
+
+// refuseSharedObject replies the conflict if the object of the file is shared
+// by other files as the new content would replace theirs as well
+func refuseSharedObject(w http.ResponseWriter, dbrep db.FileStoreRepositoryGORM, fs db.FileStore) bool {
+	if fs.Object == "" {
+		return false
+	}
+
+	_, count, err := dbrep.ReadObjectRefs(fs)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return true
+	}
+	if count > 1 {
+		displayAppError(w, RepositoryUseError,
+			"File "+*fs.UUID+" shares its object with other files, it cannot be uploaded again",
+			http.StatusConflict)
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,189 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// Assigns the file to the device group. From now on only the members
+// of the assigned groups may get it.
+func CreateFileGroupAssignment(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	var request FileGroupAssignmentRequestResource
+	err = json.Unmarshal(payload, &request)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to decode json payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+
+	if request.GroupID == 0 {
+		displayAppError(w, PayloadReadError,
+			"Missing mandatory group id",
+			http.StatusBadRequest)
+		return
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fgas, count, err := dbrep.AssignFileToGroup(id, fmt.Sprintf("%d", request.GroupID))
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while creating entity - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = FileGroupAssignmentReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   fgas,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Gets all device groups the file is assigned to
+func ReadFileGroupAssignments(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fgas, count, err := dbrep.ReadFileGroupAssignments(id)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = FileGroupAssignmentReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   fgas,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Removes the assignment of the file to the device group
+func DeleteFileGroupAssignment(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	group, err := pathVariableStr(r, "group", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable group",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable group: " + group)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	count, err := dbrep.DeleteFileGroupAssignment(id, group)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while deleting from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no assignment of file "+id+" to group: "+group,
+			http.StatusNotFound)
+		return
+	}
+
+	var reply = DeleteReplyResource{
+		Status: true,
+		Count:  count,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
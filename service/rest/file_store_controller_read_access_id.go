@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	. "fs/service/config"
 	"fs/service/db"
@@ -33,6 +35,32 @@ func ReadFileStoreAccessById(w http.ResponseWriter, r *http.Request) {
 	}
 	Log.Debug("Got path variable method: " + method)
 
+	// The download may override the response headers by the validated values
+	response := store.ResponseOverrides{
+		Disposition:  r.FormValue("disposition"),
+		ContentType:  r.FormValue("content_type"),
+		CacheControl: r.FormValue("cache_control"),
+	}
+	err = store.ValidateResponseOverrides(response)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Invalid response override - "+err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
+	// The validity of the url in seconds is clamped to the limits of the method
+	var expiresIn int64
+	if val := r.FormValue("expires_in"); val != "" {
+		expiresIn, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || expiresIn < 0 {
+			displayAppError(w, UrlPathError,
+				"Invalid expires_in - "+val,
+				http.StatusBadRequest)
+			return
+		}
+	}
+
 	dbrep, err := db.NewRepository()
 	if err != nil {
 		displayAppError(w, RepositoryNewError,
@@ -64,7 +92,16 @@ func ReadFileStoreAccessById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fsrep, err := store.NewRepository(Setup.UseFileStore, fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+	if method == "get" && refuseUnavailableFile(w, dbrep, fss[0], r.FormValue("device")) {
+		return
+	}
+
+	if method == "put" && (refuseLegalHold(w, fss[0]) || refuseSharedObject(w, dbrep, fss[0])) {
+		return
+	}
+
+	auditFile(r, fss[0])
+	fsrep, err := store.NewRepository(store.FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
 	if err != nil {
 		displayAppError(w, RepositoryNewError,
 			"Error while creating repository - "+err.Error(),
@@ -72,15 +109,33 @@ func ReadFileStoreAccessById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The limits are the ones of the tenant owning the file
+	tss, _, err := dbrep.ReadTenantStore(fss[0].TenantID)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading tenant store - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	limits, err := store.TenantPresignLimits(tss)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading tenant store - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	expiry := store.PresignExpiry(method, time.Duration(expiresIn)*time.Second, fsrep.PresignDuration(), limits)
+	fsrep = fsrep.WithPresignDuration(expiry)
+
 	// TBD - What about a map of func?
-	var url string
+	var access db.FileAccess
 	switch method {
 	case "head":
-		url, err = fsrep.FileObjectPresignedHeadURL(fss[0].CheckSum, fss[0].Size)
+		access, err = fsrep.FileObjectPresignedHeadURL(fss[0].CheckSum, fss[0].Size)
 	case "get":
-		url, err = fsrep.FileObjectPresignedGetURL(fss[0].CheckSum, fss[0].Size)
+		access, err = fsrep.FileObjectPresignedGetURL(fss[0].CheckSum, fss[0].Size, response)
 	case "put":
-		url, err = fsrep.FileObjectPresignedPutURL(fss[0].CheckSum, fss[0].Size)
+		access, err = fsrep.FileObjectPresignedPutURL(fss[0].CheckSumType, fss[0].CheckSum, fss[0].Size, fss[0].ContentType, fss[0].Metadata)
 	default:
 		err = fmt.Errorf("Invalid access method: %s, expecting: head, get, put", method)
 	}
@@ -92,7 +147,9 @@ func ReadFileStoreAccessById(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// The presigned url of the existing object both in the db as in the file store
-	fss[0].URL = url
+	// with the method and the headers to be sent along
+	fss[0].URL = access.URL
+	fss[0].Access = &access
 
 	var reply = FileStoreReplyResource{
 		Status: true,
@@ -112,7 +169,7 @@ func ReadFileStoreAccessById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	jstr := writer.Bytes()
-	Log.Debug("Reply: " + string(jstr))
+	logReply(jstr)
 
 	writeResponseWithJson(w, http.StatusOK, jstr)
 }
@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// Gets the files of the tenant available to the device: the ones it owns, the
+// ones not assigned to any group and the ones assigned to its groups
+func ReadFileStoreAvailable(w http.ResponseWriter, r *http.Request) {
+	tenant := r.FormValue("tenant")
+	if tenant == "" {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory parameter tenant",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got parameter tenant: " + tenant)
+
+	device := r.FormValue("device")
+	if device == "" {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory parameter device",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got parameter device: " + device)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadAvailableFiles(tenant, device)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = FileStoreReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   fss,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+var (
+	errDeviceMissing   = errors.New("Missing mandatory parameter device")
+	errFileUnavailable = errors.New("File is not available to the device")
+)
+
+// fileAvailable checks the device asking for the download is entitled to the
+// file either by being its owner or by the assignment of the file to its
+// groups, always within the tenant of the file
+func fileAvailable(dbrep db.FileStoreRepositoryGORM, fs db.FileStore, device string) error {
+	if device == "" {
+		return errDeviceMissing
+	}
+	available, err := dbrep.IsFileAvailable(fs, device)
+	if err != nil {
+		return fmt.Errorf("Error while checking file availability - %s", err)
+	}
+	if !available {
+		return fmt.Errorf("%w - file %s, device %s", errFileUnavailable, *fs.UUID, device)
+	}
+
+	return nil
+}
+
+// refuseUnavailableFile replies the error if the file is not available to the
+// device asking for the download
+func refuseUnavailableFile(w http.ResponseWriter, dbrep db.FileStoreRepositoryGORM, fs db.FileStore, device string) bool {
+	err := fileAvailable(dbrep, fs, device)
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, errDeviceMissing):
+		displayAppError(w, UrlPathError,
+			err.Error(),
+			http.StatusBadRequest)
+	case errors.Is(err, errFileUnavailable):
+		displayAppError(w, AuthError,
+			err.Error(),
+			http.StatusForbidden)
+	default:
+		displayAppError(w, RepositoryReadError,
+			err.Error(),
+			http.StatusInternalServerError)
+	}
+
+	return true
+}
@@ -2,13 +2,16 @@ package rest
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	. "fs/service/config"
 	"fs/service/db"
 )
 
-// Gets existing file allocation object by filter on tid, did
+// Gets existing file allocation object by filter on tid, did and optionally
+// on the metadata given as repeated metadata=key:value parameters
 func ReadFileStoreByFilter(w http.ResponseWriter, r *http.Request) {
 	tenant := r.FormValue("tenant")
 	if tenant == "" {
@@ -28,6 +31,14 @@ func ReadFileStoreByFilter(w http.ResponseWriter, r *http.Request) {
 	}
 	Log.Debug("Got parameter device: " + device)
 
+	metadata, err := metadataFilter(r)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Invalid parameter metadata - "+err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
 	dbrep, err := db.NewRepository()
 	if err != nil {
 		displayAppError(w, RepositoryNewError,
@@ -37,7 +48,7 @@ func ReadFileStoreByFilter(w http.ResponseWriter, r *http.Request) {
 	}
 	defer dbrep.Close()
 
-	fss, count, err := dbrep.ReadByFilter(tenant, device)
+	fss, count, err := dbrep.ReadByFilter(tenant, device, metadata)
 	if err != nil {
 		displayAppError(w, RepositoryReadError,
 			"Error while creating repository - "+err.Error(),
@@ -58,7 +69,30 @@ func ReadFileStoreByFilter(w http.ResponseWriter, r *http.Request) {
 			http.StatusInternalServerError)
 		return
 	}
-	Log.Debug("Reply: " + string(jstr))
+	logReply(jstr)
 	
 	writeResponseWithJson(w, http.StatusOK, jstr)
 }
+
+// metadataFilter collects the key:value pairs the files must have in metadata
+func metadataFilter(r *http.Request) (db.Metadata, error) {
+	err := r.ParseForm()
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata db.Metadata
+	for _, pair := range r.Form["metadata"] {
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("expected key:value, got: %s", pair)
+		}
+		if metadata == nil {
+			metadata = make(db.Metadata)
+		}
+		metadata[kv[0]] = kv[1]
+	}
+	Log.Debug("Got parameter metadata: " + fmt.Sprintf("%v", metadata))
+
+	return metadata, nil
+}
@@ -63,7 +63,7 @@ func ReadFileStoreById(w http.ResponseWriter, r *http.Request) {
 			http.StatusInternalServerError)
 		return
 	}
-	Log.Debug("Reply: " + string(jstr))
+	logReply(jstr)
 	
 	writeResponseWithJson(w, http.StatusOK, jstr)
 }
@@ -0,0 +1,302 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+// Lists the revisions of the logical file of the given one, the files of the
+// same tenant, device and name, the latest first
+func ReadFileStoreRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadRevisions(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no item found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = FileStoreReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   fss,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Gets the revision of the logical file of the given one by its number with
+// the presigned GET of its object, the version of it in the versioned bucket.
+// The revision is available to the device by the same rule as the file.
+func ReadFileStoreRevision(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	revision, err := pathVariableRevision(r)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
+	// The download may override the response headers by the validated values
+	response := store.ResponseOverrides{
+		Disposition:  r.FormValue("disposition"),
+		ContentType:  r.FormValue("content_type"),
+		CacheControl: r.FormValue("cache_control"),
+	}
+	err = store.ValidateResponseOverrides(response)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Invalid response override - "+err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
+	// The validity of the url in seconds is clamped to the limits of the method
+	var expiresIn int64
+	if val := r.FormValue("expires_in"); val != "" {
+		expiresIn, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || expiresIn < 0 {
+			displayAppError(w, UrlPathError,
+				"Invalid expires_in - "+val,
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadRevision(id, revision)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no revision "+strconv.Itoa(revision)+" found of id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if fss[0].Status == "N" || fss[0].Status == "M" || fss[0].Object == "" {
+		displayAppError(w, RepositoryUseError,
+			"Revision "+strconv.Itoa(revision)+" is not uploaded, status: "+fss[0].Status,
+			http.StatusConflict)
+		return
+	}
+
+	if refuseUnavailableFile(w, dbrep, fss[0], r.FormValue("device")) {
+		return
+	}
+
+	auditFile(r, fss[0])
+	fsrep, err := store.NewRepository(store.FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	// The limits are the ones of the tenant owning the file
+	tss, _, err := dbrep.ReadTenantStore(fss[0].TenantID)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading tenant store - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	limits, err := store.TenantPresignLimits(tss)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading tenant store - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	expiry := store.PresignExpiry("get", time.Duration(expiresIn)*time.Second, fsrep.PresignDuration(), limits)
+	access, err := fsrep.WithPresignDuration(expiry).WithVersion(fss[0].VersionID).
+		FileObjectPresignedGetURL(fss[0].CheckSum, fss[0].Size, response)
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while producing file object presigned url - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	fss[0].URL = access.URL
+	fss[0].Access = &access
+
+	var reply = FileStoreReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   fss,
+	}
+
+	// Eascape chars shall not be replaces by unicodes as the standard MArshall does
+	var writer bytes.Buffer
+	enc := json.NewEncoder(&writer)
+	enc.SetEscapeHTML(false)
+	err = enc.Encode(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while encoding response data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	jstr := writer.Bytes()
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Rolls the logical file of the given one back to the revision by its number.
+// The revision is copied to the new latest one, the reply is the new revision.
+func RollbackFileStoreRevision(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	revision, err := pathVariableRevision(r)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadRevision(id, revision)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no revision "+strconv.Itoa(revision)+" found of id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	fs, err := store.RollbackRevision(dbrep, fss[0])
+	if errors.Is(err, store.ErrRevisionNotUploaded) {
+		displayAppError(w, RepositoryUseError,
+			"Revision "+strconv.Itoa(revision)+" is not uploaded, status: "+fss[0].Status,
+			http.StatusConflict)
+		return
+	}
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while rolling back file - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	auditDetail(r, "rollback of "+id+" to revision "+strconv.Itoa(revision))
+	auditFile(r, fs)
+
+	var reply = FileStoreReplyResource{
+		Status: true,
+		Count:  1,
+		Data:   []db.FileStore{fs},
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusCreated, jstr)
+}
+
+// pathVariableRevision is the number of the revision in the url path
+func pathVariableRevision(r *http.Request) (int, error) {
+	val, err := pathVariableStr(r, "revision", true)
+	if err != nil {
+		return 0, errors.New("Missing mandatory url path variable revision")
+	}
+	revision, err := strconv.Atoi(val)
+	if err != nil || revision <= 0 {
+		return 0, errors.New("Invalid revision - " + val)
+	}
+	Log.Debug("Got path variable revision: " + val)
+
+	return revision, nil
+}
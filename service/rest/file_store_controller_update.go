@@ -3,9 +3,11 @@ package rest
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 	
 	. "fs/service/config"
 	"fs/service/db"
+	"fs/service/store"
 )
 
 // Updates the metricsus of the existing file allocation object by primary key
@@ -46,7 +48,79 @@ func UpdateFileStoreById(w http.ResponseWriter, r *http.Request) {
 	}
 	defer dbrep.Close()
 
-	fss, count, err := dbrep.UpdateById(id, request.Status, request.CheckSum, request.Size)
+	fss, count, err := dbrep.ReadById(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no item found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if refuseLegalHold(w, fss[0]) {
+		return
+	}
+	auditFile(r, fss[0])
+
+	// The content is marked verified or mismatching by the verification only
+	if request.Status == "C" || request.Status == "X" {
+		displayAppError(w, PayloadReadError,
+			"Status "+request.Status+" is set by the checksum verification only",
+			http.StatusBadRequest)
+		return
+	}
+
+	// The new checksum is validated with its algorithm, the one of the record
+	// if not given in the request. The algorithm is not changed without the
+	// checksum, the one of the record would be labelled wrong.
+	request.CheckSumType = strings.ToUpper(request.CheckSumType)
+	if request.CheckSumType != "" {
+		err = store.ValidateChecksumType(request.CheckSumType)
+		if err != nil {
+			displayAppError(w, PayloadReadError,
+				"Invalid checksum - "+err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+		if request.CheckSum == "" && request.CheckSumType != fss[0].CheckSumType {
+			displayAppError(w, PayloadReadError,
+				"Checksum type "+request.CheckSumType+" given without the checksum",
+				http.StatusBadRequest)
+			return
+		}
+	}
+	if request.CheckSum != "" {
+		cksumType := request.CheckSumType
+		if cksumType == "" {
+			cksumType = fss[0].CheckSumType
+		}
+		request.CheckSum, err = store.ChecksumBase64(cksumType, request.CheckSum)
+		if err != nil {
+			displayAppError(w, PayloadReadError,
+				"Invalid checksum - "+err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The verified content keeps the checksum and size it was verified with
+	if fss[0].VerifiedCheckSum != "" &&
+		((request.CheckSum != "" && request.CheckSum != fss[0].VerifiedCheckSum) ||
+			(request.CheckSumType != "" && request.CheckSumType != fss[0].CheckSumType) ||
+			(request.Size != 0 && request.Size != fss[0].Size)) {
+		displayAppError(w, RepositoryUseError,
+			"File "+id+" content is verified, its checksum and size cannot be changed",
+			http.StatusConflict)
+		return
+	}
+
+	fss, count, err = dbrep.UpdateById(id, request.Status, request.CheckSumType, request.CheckSum, request.Size)
 	if err != nil {
 		displayAppError(w, RepositoryWriteError,
 			"Error while creating repository - "+err.Error(),
@@ -54,10 +128,11 @@ func UpdateFileStoreById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The legal hold may have been set since the file was read
 	if count == 0 {
-		displayAppError(w, RepositoryReadError,
-			"Error no item updated by id - "+err.Error(),
-			http.StatusNotFound)
+		displayAppError(w, RepositoryUseError,
+			"File "+id+" is under legal hold, it cannot be changed",
+			http.StatusConflict)
 		return
 	}
 
@@ -67,7 +142,7 @@ func UpdateFileStoreById(w http.ResponseWriter, r *http.Request) {
 			http.StatusInternalServerError)
 		return
 	}
-	
+
 	var reply = FileStoreReplyResource{
 		Status: true,
 		Count:  count,
@@ -81,7 +156,7 @@ func UpdateFileStoreById(w http.ResponseWriter, r *http.Request) {
 			http.StatusInternalServerError)
 		return
 	}
-	Log.Debug("Reply: " + string(jstr))
+	logReply(jstr)
 	
 	writeResponseWithJson(w, http.StatusOK, jstr)
 }
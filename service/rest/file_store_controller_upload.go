@@ -0,0 +1,619 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+// CreateFileStoreUpload starts the multipart upload of the existing file
+// store resource. It returns presigned PUT URL for each part of the object.
+// The parts may be uploaded independently and in any order.
+func CreateFileStoreUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	// The payload is optional, the configured part size is used by default
+	var request FileStoreUploadRequestResource
+	if len(payload) > 0 {
+		err = json.Unmarshal(payload, &request)
+		if err != nil {
+			displayAppError(w, PayloadReadError,
+				"Unable to decode json payload of the request",
+				http.StatusBadRequest)
+			return
+		}
+	}
+	if request.PartSize == 0 {
+		request.PartSize = Setup.MultipartPartSize
+	}
+	if request.ExpiresIn < 0 {
+		displayAppError(w, PayloadReadError,
+			"Invalid expires_in - "+fmt.Sprintf("%d", request.ExpiresIn),
+			http.StatusBadRequest)
+		return
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadById(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no item found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if fss[0].Status == "M" {
+		displayAppError(w, RepositoryUseError,
+			"Multipart upload already in progress for id: "+id,
+			http.StatusConflict)
+		return
+	}
+	if refuseLegalHold(w, fss[0]) || refuseSharedObject(w, dbrep, fss[0]) {
+		return
+	}
+
+	sizes, err := store.MultipartPartSizes(fss[0].Size, request.PartSize)
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while splitting file object into parts - "+err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
+	auditFile(r, fss[0])
+	fsrep, err := store.NewRepository(store.FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	expiry, err := partPresignExpiry(dbrep, fss[0], request.ExpiresIn, fsrep.PresignDuration())
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading tenant store - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	fsrep = fsrep.WithPresignDuration(expiry)
+
+	// The file is taken for the upload first so that the concurrent request
+	// does not start the other one, it is given back if the upload fails
+	reserved, err := dbrep.ReserveMultipartUpload(fss[0].ID)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while registering multipart upload - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	if reserved == 0 {
+		displayAppError(w, RepositoryUseError,
+			"Multipart upload already in progress for id: "+id,
+			http.StatusConflict)
+		return
+	}
+	status := fss[0].Status
+	release := func(uploadId string) {
+		if uploadId != "" {
+			aerr := fsrep.FileObjectMultipartAbort(uploadId)
+			if aerr != nil {
+				Log.Error("Error while aborting multipart upload " + uploadId + " - " + aerr.Error())
+			}
+		}
+		rerr := dbrep.ReleaseMultipartUpload(fss[0].ID, status)
+		if rerr != nil {
+			Log.Error("Error while releasing file " + id + " reserved for multipart upload - " + rerr.Error())
+		}
+	}
+
+	uploadId, err := fsrep.FileObjectMultipartCreate(fss[0].ContentType, fss[0].Metadata)
+	if err != nil {
+		release("")
+		displayAppError(w, RepositoryUseError,
+			"Error while starting multipart upload - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	// Each part gets its own presigned URL bound to the part number and size
+	parts := make([]db.FilePart, len(sizes))
+	for i, size := range sizes {
+		number := int64(i + 1)
+		access, err := fsrep.FileObjectPresignedPartURL(uploadId, number, size)
+		if err != nil {
+			release(uploadId)
+			displayAppError(w, RepositoryUseError,
+				"Error while allocating file object part url - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		parts[i] = db.FilePart{
+			Number: number,
+			Size:   size,
+			URL:    access.URL,
+			Access: &access,
+		}
+	}
+
+	err = dbrep.SetMultipartUpload(fss[0].ID, uploadId, sizes[0], int64(len(sizes)))
+	if err != nil {
+		release(uploadId)
+		displayAppError(w, RepositoryWriteError,
+			"Error while registering multipart upload - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	Log.Debug("Started multipart upload: " + uploadId + fmt.Sprintf(" with %d parts", len(sizes)))
+
+	fss[0].Status = "M"
+	fss[0].UploadID = uploadId
+	fss[0].PartSize = sizes[0]
+	fss[0].PartCount = int64(len(sizes))
+	fss[0].PartsUploaded = 0
+	fss[0].Parts = parts
+
+	var reply = FileStoreReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   fss,
+	}
+
+	// Eascape chars shall not be replaces by unicodes as the standard MArshall does
+	var writer bytes.Buffer
+	enc := json.NewEncoder(&writer)
+	enc.SetEscapeHTML(false)
+	err = enc.Encode(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while encoding response data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	jstr := writer.Bytes()
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// ReadFileStoreUpload reports the multipart upload in progress. The parts
+// already received by the store are returned with their etags and the missing
+// parts with fresh presigned PUT URL so that the upload may be resumed. The
+// number of the received parts is the progress of the upload, it is listed
+// from the store on each read and not kept in the db.
+func ReadFileStoreUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	// The validity of the part urls in seconds is clamped to the limits of put
+	var expiresIn int64
+	if val := r.FormValue("expires_in"); val != "" {
+		expiresIn, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || expiresIn < 0 {
+			displayAppError(w, UrlPathError,
+				"Invalid expires_in - "+val,
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadById(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no item found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if fss[0].Status != "M" {
+		displayAppError(w, RepositoryUseError,
+			"No multipart upload in progress for id: "+id,
+			http.StatusConflict)
+		return
+	}
+
+	// The part layout is the same as when the upload was started
+	sizes, err := store.MultipartPartSizes(fss[0].Size, fss[0].PartSize)
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while splitting file object into parts - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	auditFile(r, fss[0])
+	fsrep, err := store.NewRepository(store.FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	expiry, err := partPresignExpiry(dbrep, fss[0], expiresIn, fsrep.PresignDuration())
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading tenant store - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	fsrep = fsrep.WithPresignDuration(expiry)
+
+	received, err := fsrep.FileObjectMultipartParts(fss[0].UploadID)
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while listing multipart upload parts - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	etags := make(map[int64]string)
+	for _, part := range received {
+		etags[part.Number] = part.ETag
+	}
+
+	// Only the missing parts get the presigned URL
+	parts := make([]db.FilePart, len(sizes))
+	for i, size := range sizes {
+		number := int64(i + 1)
+		parts[i] = db.FilePart{
+			Number: number,
+			Size:   size,
+		}
+		if etag, ok := etags[number]; ok {
+			parts[i].ETag = etag
+			continue
+		}
+		access, err := fsrep.FileObjectPresignedPartURL(fss[0].UploadID, number, size)
+		if err != nil {
+			displayAppError(w, RepositoryUseError,
+				"Error while allocating file object part url - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		parts[i].URL = access.URL
+		parts[i].Access = &access
+	}
+
+	fss[0].PartsUploaded = int64(len(etags))
+	fss[0].Parts = parts
+
+	var reply = FileStoreReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   fss,
+	}
+
+	// Eascape chars shall not be replaces by unicodes as the standard MArshall does
+	var writer bytes.Buffer
+	enc := json.NewEncoder(&writer)
+	enc.SetEscapeHTML(false)
+	err = enc.Encode(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while encoding response data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	jstr := writer.Bytes()
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// CompleteFileStoreUpload assembles the object from all uploaded parts
+// identified by part numbers and etags returned by the store on the part
+// uploads. The file is (U)ploaded, the parts carry no checksum of the whole
+// content so it is not verified.
+func CompleteFileStoreUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	var request FileStoreUploadRequestResource
+	err = json.Unmarshal(payload, &request)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to decode json payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadById(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no item found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if fss[0].Status != "M" {
+		displayAppError(w, RepositoryUseError,
+			"No multipart upload in progress for id: "+id,
+			http.StatusConflict)
+		return
+	}
+	if refuseLegalHold(w, fss[0]) {
+		return
+	}
+
+	// All parts must be reported in order with their etags
+	if int64(len(request.Parts)) != fss[0].PartCount {
+		displayAppError(w, PayloadReadError,
+			fmt.Sprintf("Invalid number of parts: %d, expected: %d", len(request.Parts), fss[0].PartCount),
+			http.StatusBadRequest)
+		return
+	}
+	for i, part := range request.Parts {
+		if part.Number != int64(i+1) || part.ETag == "" {
+			displayAppError(w, PayloadReadError,
+				fmt.Sprintf("Invalid part: %d, expected ordered part numbers with etags", part.Number),
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	auditFile(r, fss[0])
+	fsrep, err := store.NewRepository(store.FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	// The parts must be the ones the object was split into on create
+	sizes, err := store.MultipartPartSizes(fss[0].Size, fss[0].PartSize)
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while splitting file object into parts - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	received, err := fsrep.FileObjectMultipartParts(fss[0].UploadID)
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while listing multipart upload parts - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	err = store.ValidateMultipartParts(sizes, request.Parts, received)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Invalid multipart upload parts - "+err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
+	err = fsrep.FileObjectMultipartComplete(fss[0].UploadID, request.Parts)
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while completing multipart upload - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	err = dbrep.CompleteMultipartUpload(fss[0].ID)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while registering multipart upload completion - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	fss[0].Status = "U"
+	fss[0].UploadID = ""
+	fss[0].PartsUploaded = fss[0].PartCount
+
+	var reply = FileStoreReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   fss,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// DeleteFileStoreUpload aborts the multipart upload in progress. The parts
+// uploaded so far are discarded by the store.
+func DeleteFileStoreUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadById(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no item found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if fss[0].Status != "M" {
+		displayAppError(w, RepositoryUseError,
+			"No multipart upload in progress for id: "+id,
+			http.StatusConflict)
+		return
+	}
+
+	auditFile(r, fss[0])
+	fsrep, err := store.NewRepository(store.FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	err = fsrep.FileObjectMultipartAbort(fss[0].UploadID)
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while aborting multipart upload - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	err = dbrep.AbortMultipartUpload(fss[0].ID)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while registering multipart upload abort - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	fss[0].Status = "N"
+	fss[0].UploadID = ""
+	fss[0].PartSize = 0
+	fss[0].PartCount = 0
+	fss[0].PartsUploaded = 0
+
+	var reply = FileStoreReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   fss,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// partPresignExpiry is the validity of the part urls requested by the client
+// clamped to the PUT limits of the tenant owning the file
+func partPresignExpiry(dbrep db.FileStoreRepositoryGORM, fs db.FileStore, expiresIn int64, fallback time.Duration) (time.Duration, error) {
+	tss, _, err := dbrep.ReadTenantStore(fs.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	limits, err := store.TenantPresignLimits(tss)
+	if err != nil {
+		return 0, err
+	}
+
+	return store.PresignExpiry("put", time.Duration(expiresIn)*time.Second, fallback, limits), nil
+}
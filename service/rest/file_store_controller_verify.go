@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+// VerifyFileStoreById checks with HEAD on the uploaded object that the checksum
+// calculated by the store matches the one declared by the client on create.
+// The status becomes (C)reated on match or (X) on checksum mismatch, the object
+// of the multipart upload is (U)ploaded with no checksum to verify.
+func VerifyFileStoreById(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadById(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no item found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	auditFile(r, fss[0])
+	fsrep, err := store.NewRepository(store.FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	status, verified, err := store.VerifyFile(fsrep, fss[0])
+	if err != nil {
+		displayAppError(w, RepositoryUseError,
+			"Error while verifying file object checksum - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	err = dbrep.UpdateStatusById(fss[0].ID, status, verified)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while updating status - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	fss[0].Status = status
+
+	// The revision is pinned to the version of the object in the versioned bucket
+	if status == "C" || status == "U" {
+		version, err := fsrep.FileObjectVersion()
+		if err != nil {
+			displayAppError(w, RepositoryUseError,
+				"Error while reading file object version - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		if version != "" {
+			err = dbrep.SetVersionID(fss[0].ID, version)
+			if err != nil {
+				displayAppError(w, RepositoryWriteError,
+					"Error while updating version - "+err.Error(),
+					http.StatusInternalServerError)
+				return
+			}
+			fss[0].VersionID = version
+		}
+	}
+
+	var reply = FileStoreReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   fss,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
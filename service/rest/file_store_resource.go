@@ -7,10 +7,41 @@ import (
 type (
 	// input: for Create entity of S3 bucket file allocation object
 	FileStoreRequestResource struct {
-		CheckSum string `json:"check_sum,omitempty"`
-		Name     string `json:"name,omitempty"`
-		Size     int64  `json:"size,omitempty"`
-		Status   string `json:"status,omitempty"`
+		CheckSumType string            `json:"check_sum_type,omitempty"`
+		CheckSum     string            `json:"check_sum,omitempty"`
+		Name         string            `json:"name,omitempty"`
+		Size         int64             `json:"size,omitempty"`
+		Status       string            `json:"status,omitempty"`
+		ContentType  string            `json:"content_type,omitempty"`
+		ExpiresIn    int64             `json:"expires_in,omitempty"`
+		Metadata     map[string]string `json:"metadata,omitempty"`
+	}
+
+	// input: for multipart upload of S3 bucket file object, the part size
+	// and the validity of the part urls when starting it and the etags of the
+	// uploaded parts when completing it
+	FileStoreUploadRequestResource struct {
+		PartSize  int64         `json:"part_size,omitempty"`
+		ExpiresIn int64         `json:"expires_in,omitempty"`
+		Parts     []db.FilePart `json:"parts,omitempty"`
+	}
+
+	// input: for setting the legal hold of the file with the reason, ex.
+	// the reference of the investigation
+	FileStoreLegalHoldRequestResource struct {
+		Reason string `json:"reason,omitempty"`
+	}
+
+	// input: for Create of many entities of the device at once
+	FileStoreBatchRequestResource struct {
+		Files []FileStoreRequestResource `json:"files"`
+	}
+
+	// input: for GET or HEAD access to many existing objects at once
+	FileStoreAccessBatchRequestResource struct {
+		Method    string   `json:"method"`
+		IDs       []string `json:"ids"`
+		ExpiresIn int64    `json:"expires_in,omitempty"`
 	}
 
 	// output: S3 bucket file allocation object id with an access URL address from POST
@@ -19,4 +50,21 @@ type (
 		Count  int64          `json:"count"`
 		Data   []db.FileStore `json:"data,omitempt"`
 	}
+
+	// output: the file of the item of the batch by its index in the request
+	// or the error of the item, the id of the file is given if known
+	FileStoreBatchItemResource struct {
+		Index int           `json:"index"`
+		ID    string        `json:"id,omitempty"`
+		Error string        `json:"error,omitempty"`
+		File  *db.FileStore `json:"file,omitempty"`
+	}
+
+	// output: the items of the batch with the number of the failed ones
+	FileStoreBatchReplyResource struct {
+		Status bool                         `json:"status"`
+		Count  int64                        `json:"count"`
+		Failed int64                        `json:"failed"`
+		Data   []FileStoreBatchItemResource `json:"data,omitempty"`
+	}
 )
@@ -2,6 +2,8 @@ package rest
 
 import (
 	"github.com/gorilla/mux"
+
+	. "fs/service/config"
 )
 
 // NewFileStoreRouter creates the router for file service API
@@ -14,18 +16,99 @@ func NewFileStoreRouter(r *mux.Router) *mux.Router {
 		Methods("POST").
 		Name("CreateFileStore")
 
+	// Creates many records of the device in one transaction producing
+	// presigned PUT URL for each, the failed items are reported one by one.
+	r.HandleFunc("/api/v1/files:batch",
+		CreateFileStoreBatch).
+		Methods("POST").
+		Name("CreateFileStoreBatch")
+
+	// Produces presigned GET or HEAD URL for each of the listed existing
+	// objects, the failed items are reported one by one.
+	r.HandleFunc("/api/v1/files:access",
+		ReadFileStoreAccessBatch).
+		Methods("POST").
+		Name("ReadFileStoreAccessBatch")
+
 	// Produces presigned URL for GET, PUT, HEAD methods on existing object
-	// by numeric primary key. It accesses the db to get the record with key
+	// by public id. It accesses the db to get the record with key
 	// data and then it can access data store bucket where the object is located.
 	// Only one object may exist in the db.
-	r.HandleFunc("/api/v1/files/{id}/{method:get|put|head}",
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/{method:get|put|head}",
 		ReadFileStoreAccessById).
 		Methods("GET").
 		Name("ReadfileStoreAccessById")
 	
-	// Gets existing db file info by numeric primary key not accessing
+	// Verifies the checksum of the uploaded object with HEAD on the store
+	// and sets the status accordingly.
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/verify",
+		VerifyFileStoreById).
+		Methods("POST").
+		Name("VerifyFileStoreById")
+
+	// Starts multipart upload of existing object producing presigned PUT URL
+	// for each part. It is used for large objects or unreliable links.
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/upload",
+		CreateFileStoreUpload).
+		Methods("POST").
+		Name("CreateFileStoreUpload")
+
+	// Gets the status of multipart upload with the parts already received
+	// and fresh presigned PUT URL for each missing part. It allows to resume
+	// the upload after the original URLs expired.
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/upload",
+		ReadFileStoreUpload).
+		Methods("GET").
+		Name("ReadFileStoreUpload")
+
+	// Completes multipart upload with the etags of all uploaded parts.
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/upload/complete",
+		CompleteFileStoreUpload).
+		Methods("POST").
+		Name("CompleteFileStoreUpload")
+
+	// Aborts multipart upload discarding the parts uploaded so far.
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/upload",
+		DeleteFileStoreUpload).
+		Methods("DELETE").
+		Name("DeleteFileStoreUpload")
+
+	// Lists the revisions of the logical file, the files uploaded with
+	// the same tenant, device and name, the latest first.
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/revisions",
+		ReadFileStoreRevisions).
+		Methods("GET").
+		Name("ReadFileStoreRevisions")
+
+	// Gets the revision by its number with presigned GET URL of its object.
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/revisions/{revision:[0-9]+}",
+		ReadFileStoreRevision).
+		Methods("GET").
+		Name("ReadFileStoreRevision")
+
+	// Rolls back to the revision copying it to the new latest revision.
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/revisions/{revision:[0-9]+}/rollback",
+		RollbackFileStoreRevision).
+		Methods("POST").
+		Name("RollbackFileStoreRevision")
+
+	// In proxy mode the content is streamed through the service for the devices
+	// that cannot reach the store directly.
+	if Setup.ProxyMode {
+		r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/content",
+			UploadFileStoreContent).
+			Methods("PUT").
+			Name("UploadFileStoreContent")
+
+		r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}/content",
+			DownloadFileStoreContent).
+			Methods("GET").
+			Name("DownloadFileStoreContent")
+	}
+
+	// Gets existing db file info by public id not accessing
 	// the data store with object metadata. It must return only one object.
-	r.HandleFunc("/api/v1/files/{id:[0-9]+}",
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}",
 		ReadFileStoreById).
 		Methods("GET").
 		Name("ReadFileStoreById")
@@ -37,9 +120,9 @@ func NewFileStoreRouter(r *mux.Router) *mux.Router {
 		Methods("GET").
 		Name("ReadFileStoreByFilter")
 	
-	// Updates the status of the db file info by numeric primary key. Only one
+	// Updates the status of the db file info by public id. Only one
 	// object can be accessed.
-	r.HandleFunc("/api/v1/files/{id:[0-9]+}",
+	r.HandleFunc("/api/v1/files/{id:[0-9a-f-]{36}}",
 		UpdateFileStoreById).
 		Methods("PATCH").
 		Name("UpdateFileStoreById")
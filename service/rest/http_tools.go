@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"regexp"
 
 	"github.com/gorilla/mux"
+
+	. "fs/service/config"
 )
 
 //
@@ -20,6 +23,17 @@ func writeResponseWithJson(w http.ResponseWriter, status int, payload []byte) {
 	}
 }
 
+// The SSE-C key sent with the presigned requests is the secret of the tenant,
+// it is never written to the log
+var sseCustomerKeyPattern = regexp.MustCompile(`(?i)("x-amz-server-side-encryption-customer-key"\s*:\s*)"[^"]*"`)
+
+//
+// logReply logs the reply payload with the SSE-C keys redacted
+//
+func logReply(payload []byte) {
+	Log.Debug("Reply: " + sseCustomerKeyPattern.ReplaceAllString(string(payload), `$1"REDACTED"`))
+}
+
 //
 // pathVariableStr gets & validates existence of string parameter
 //
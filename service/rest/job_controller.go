@@ -0,0 +1,262 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+const (
+	// The number of the jobs listed by default and at most
+	jobListLimit    = 100
+	jobListLimitMax = 1000
+)
+
+// Submits the job of the type to be run by the workers of any replica,
+// the payload is validated by the type. The reply is the pending job
+// to be polled by its id.
+func CreateJob(w http.ResponseWriter, r *http.Request) {
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	var request JobRequestResource
+	err = json.Unmarshal(payload, &request)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to decode json payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+
+	err = store.ValidateJob(request.Type, request.Payload)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+	if request.MaxAttempts < 0 {
+		displayAppError(w, PayloadReadError,
+			"Invalid max_attempts - "+strconv.Itoa(request.MaxAttempts),
+			http.StatusBadRequest)
+		return
+	}
+	if request.MaxAttempts == 0 {
+		request.MaxAttempts = Setup.JobMaxAttempts
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	js, count, err := dbrep.CreateJob(request.Type, request.TenantID, request.Payload, request.MaxAttempts)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while creating entity - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	Log.Info("Submitted job " + *js[0].UUID + " of type " + request.Type)
+
+	var reply = JobReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   js,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusAccepted, jstr)
+}
+
+// Lists the latest jobs optionally filtered by the type, the tenant
+// and the state, at most the limit of them
+func ReadJobs(w http.ResponseWriter, r *http.Request) {
+	limit := jobListLimit
+	if val := r.FormValue("limit"); val != "" {
+		var err error
+		limit, err = strconv.Atoi(val)
+		if err != nil || limit <= 0 || limit > jobListLimitMax {
+			displayAppError(w, UrlPathError,
+				"Invalid limit - "+val,
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	js, count, err := dbrep.ReadJobs(r.FormValue("type"), r.FormValue("tenant"), r.FormValue("state"), limit)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = JobReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   js,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Gets the job with its state, progress and result
+func ReadJobById(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	js, count, err := dbrep.ReadJobById(id)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no job found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	var reply = JobReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   js,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Cancels the pending or running job, the running one is stopped by its
+// worker on the next lease renewal. The job already finished is a conflict.
+func CancelJob(w http.ResponseWriter, r *http.Request) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	cancelled, err := dbrep.CancelJob(id)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while writing to db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	js, count, err := dbrep.ReadJobById(id)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no job found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+	if cancelled == 0 {
+		displayAppError(w, RepositoryUseError,
+			"Job "+id+" is finished, state: "+js[0].State,
+			http.StatusConflict)
+		return
+	}
+	Log.Info("Cancelled job " + id + " of type " + js[0].Type)
+
+	var reply = JobReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   js,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
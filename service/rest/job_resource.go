@@ -0,0 +1,24 @@
+package rest
+
+import (
+	"fs/service/db"
+)
+
+type (
+	// input: for Create of the job of the type with its payload, the tenant is
+	// optional for the maintenance jobs and the attempts are the configured ones
+	// unless given
+	JobRequestResource struct {
+		Type        string        `json:"type"`
+		TenantID    string        `json:"tenant,omitempty"`
+		Payload     db.JobPayload `json:"payload,omitempty"`
+		MaxAttempts int           `json:"max_attempts,omitempty"`
+	}
+
+	// output: jobs with their state and progress
+	JobReplyResource struct {
+		Status bool     `json:"status"`
+		Count  int64    `json:"count"`
+		Data   []db.Job `json:"data,omitempty"`
+	}
+)
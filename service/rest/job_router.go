@@ -0,0 +1,35 @@
+package rest
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// NewJobRouter creates the router for the long running jobs run
+// by the workers of any replica
+func NewJobRouter(r *mux.Router) *mux.Router {
+	// Submits the job to be run by the workers
+	r.HandleFunc("/api/v1/jobs",
+		CreateJob).
+		Methods("POST").
+		Name("CreateJob")
+
+	// Lists the latest jobs filtered by type, tenant and state
+	r.HandleFunc("/api/v1/jobs",
+		ReadJobs).
+		Methods("GET").
+		Name("ReadJobs")
+
+	// Gets the job with its progress and result
+	r.HandleFunc("/api/v1/jobs/{id:[0-9a-f-]{36}}",
+		ReadJobById).
+		Methods("GET").
+		Name("ReadJobById")
+
+	// Cancels the pending or running job
+	r.HandleFunc("/api/v1/jobs/{id:[0-9a-f-]{36}}",
+		CancelJob).
+		Methods("DELETE").
+		Name("CancelJob")
+
+	return r
+}
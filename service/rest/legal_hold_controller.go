@@ -0,0 +1,163 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+// Sets the legal hold of the file so that it is not deleted by any means,
+// the retention included. The hold is set on the object as well where the
+// bucket has the object lock enabled.
+func SetFileLegalHold(w http.ResponseWriter, r *http.Request) {
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	// The payload is optional, the hold with no reason by default
+	var request FileStoreLegalHoldRequestResource
+	if len(payload) > 0 {
+		err = json.Unmarshal(payload, &request)
+		if err != nil {
+			displayAppError(w, PayloadReadError,
+				"Unable to decode json payload of the request",
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	updateFileLegalHold(w, r, true, request.Reason)
+}
+
+// Clears the legal hold of the file, the file is subject to the retention again
+func ClearFileLegalHold(w http.ResponseWriter, r *http.Request) {
+	updateFileLegalHold(w, r, false, "")
+}
+
+// updateFileLegalHold sets or clears the legal hold of the object first,
+// so that the db never shows the file released while the store holds it
+func updateFileLegalHold(w http.ResponseWriter, r *http.Request, hold bool, reason string) {
+	id, err := pathVariableStr(r, "id", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable id",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable id: " + id)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	fss, count, err := dbrep.ReadById(id)
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no item found by id: "+id,
+			http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	auditFile(r, fss[0])
+	auditDetail(r, reason)
+
+	// The hold of the file not uploaded yet could not be set on its object,
+	// the upload completing later would leave the object released
+	uploaded := fss[0].Status != "N" && fss[0].Status != "M"
+	if hold && !uploaded {
+		displayAppError(w, RepositoryUseError,
+			"File "+id+" is not uploaded yet, it cannot be held",
+			http.StatusConflict)
+		return
+	}
+
+	// The object shared with the other file still under legal hold stays held
+	objectHold := uploaded
+	if objectHold && !hold {
+		refs, _, err := dbrep.ReadObjectRefs(fss[0])
+		if err != nil {
+			displayAppError(w, RepositoryReadError,
+				"Error while reading from db repository - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		for _, ref := range refs {
+			if ref.ID != fss[0].ID && ref.LegalHold {
+				Log.Info("Legal hold of object of file " + id + " kept for file " + *ref.UUID)
+				objectHold = false
+				break
+			}
+		}
+	}
+
+	if objectHold {
+		fsrep, err := store.NewRepository(store.FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+		if err != nil {
+			displayAppError(w, RepositoryNewError,
+				"Error while creating repository - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+
+		err = fsrep.WithVersion(fss[0].VersionID).FileObjectLegalHold(hold)
+		if errors.Is(err, store.ErrObjectLockUnsupported) {
+			Log.Info("Legal hold of file " + id + " kept by db only - " + err.Error())
+		} else if err != nil {
+			displayAppError(w, RepositoryUseError,
+				"Error while setting legal hold of file object - "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+	}
+
+	fss, count, err = dbrep.SetLegalHold(id, hold, reason)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while writing to db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	if hold {
+		Log.Info("Set legal hold of file " + id + " reason: " + reason)
+	} else {
+		Log.Info("Cleared legal hold of file " + id)
+	}
+
+	var reply = FileStoreReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   fss,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
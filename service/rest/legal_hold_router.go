@@ -0,0 +1,23 @@
+package rest
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// NewLegalHoldRouter creates the router for admin API of the legal hold
+// of the files which must not be deleted
+func NewLegalHoldRouter(r *mux.Router) *mux.Router {
+	// Sets the legal hold of the file
+	r.HandleFunc("/api/v1/admin/files/{id:[0-9a-f-]{36}}/legal-hold",
+		SetFileLegalHold).
+		Methods("PUT").
+		Name("SetFileLegalHold")
+
+	// Clears the legal hold of the file
+	r.HandleFunc("/api/v1/admin/files/{id:[0-9a-f-]{36}}/legal-hold",
+		ClearFileLegalHold).
+		Methods("DELETE").
+		Name("ClearFileLegalHold")
+
+	return r
+}
@@ -0,0 +1,340 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+const (
+	// The number of the files listed by the retention report by default and at most
+	retentionReportLimit    = 100
+	retentionReportLimitMax = 10000
+)
+
+// Gets the retention rules of the tenant
+func ReadRetentionRules(w http.ResponseWriter, r *http.Request) {
+	tenant, err := pathVariableStr(r, "tenant", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable tenant",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable tenant: " + tenant)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	rrs, count, err := dbrep.ReadRetentionRules(tenant)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = RetentionRuleReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   rrs,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Sets the retention rule of the tenant files of the category, the files
+// outside the rule are removed by the next enforcement
+func UpdateRetentionRule(w http.ResponseWriter, r *http.Request) {
+	tenant, err := pathVariableStr(r, "tenant", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable tenant",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable tenant: " + tenant)
+
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	var request RetentionRuleRequestResource
+	err = json.Unmarshal(payload, &request)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to decode json payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+
+	if request.MaxAgeDays < 0 || request.MaxCount < 0 || request.MaxBytes < 0 {
+		displayAppError(w, PayloadReadError,
+			"Invalid retention rule, the limits must not be negative",
+			http.StatusBadRequest)
+		return
+	}
+	if request.MaxAgeDays == 0 && request.MaxCount == 0 && request.MaxBytes == 0 {
+		displayAppError(w, PayloadReadError,
+			"Invalid retention rule, at least one of max_age_days, max_count, max_bytes is needed",
+			http.StatusBadRequest)
+		return
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	rrs, count, err := dbrep.UpsertRetentionRule(db.RetentionRule{
+		TenantID:   tenant,
+		Category:   request.Category,
+		MaxAgeDays: request.MaxAgeDays,
+		MaxCount:   request.MaxCount,
+		MaxBytes:   request.MaxBytes,
+	})
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while writing to db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	Log.Info("Set retention rule of tenant " + tenant + " category " + request.Category)
+
+	var reply = RetentionRuleReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   rrs,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Removes the retention rule of the tenant files of the category given by
+// the category parameter, the rule with no category if not given
+func DeleteRetentionRule(w http.ResponseWriter, r *http.Request) {
+	tenant, err := pathVariableStr(r, "tenant", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable tenant",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable tenant: " + tenant)
+	category := r.FormValue("category")
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	count, err := dbrep.DeleteRetentionRule(tenant, category)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while deleting from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no retention rule of tenant: "+tenant+" category: "+category,
+			http.StatusNotFound)
+		return
+	}
+	Log.Info("Removed retention rule of tenant " + tenant + " category " + category)
+
+	var reply = DeleteReplyResource{
+		Status: true,
+		Count:  count,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Lists the tenant files the rules would remove with the rule and the reason,
+// at most the limit of them, nothing is removed
+func ReadRetentionReport(w http.ResponseWriter, r *http.Request) {
+	tenant, err := pathVariableStr(r, "tenant", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable tenant",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable tenant: " + tenant)
+
+	limit := retentionReportLimit
+	if val := r.FormValue("limit"); val != "" {
+		limit, err = strconv.Atoi(val)
+		if err != nil || limit <= 0 || limit > retentionReportLimitMax {
+			displayAppError(w, UrlPathError,
+				"Invalid limit - "+val,
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	items, err := store.RetentionReport(dbrep, tenant, limit)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = RetentionReportReplyResource{
+		Status: true,
+		Count:  int64(len(items)),
+		Data:   items,
+	}
+	for _, item := range items {
+		reply.Bytes += item.Size
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Submits the job enforcing the retention rules of the tenant now rather
+// than on the next scheduled run
+func EnforceRetentionRules(w http.ResponseWriter, r *http.Request) {
+	tenant, err := pathVariableStr(r, "tenant", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable tenant",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable tenant: " + tenant)
+
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+
+	// The payload is optional, the files are removed by default
+	var request RetentionEnforceRequestResource
+	if len(payload) > 0 {
+		err = json.Unmarshal(payload, &request)
+		if err != nil {
+			displayAppError(w, PayloadReadError,
+				"Unable to decode json payload of the request",
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	payload, err = json.Marshal(map[string]interface{}{"tenant": tenant, "dry_run": request.DryRun})
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	js, count, err := dbrep.CreateJob(store.JobTypeRetention, tenant, payload, Setup.JobMaxAttempts)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while creating job - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	Log.Info("Submitted retention job " + *js[0].UUID + " of tenant " + tenant)
+
+	var reply = JobReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   js,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusAccepted, jstr)
+}
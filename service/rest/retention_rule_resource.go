@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"fs/service/db"
+	"fs/service/store"
+)
+
+type (
+	// input: for setting the retention rule of the tenant files of the category,
+	// the rule with no category covers the files of the other categories
+	RetentionRuleRequestResource struct {
+		Category   string `json:"category,omitempty"`
+		MaxAgeDays int64  `json:"max_age_days,omitempty"`
+		MaxCount   int64  `json:"max_count,omitempty"`
+		MaxBytes   int64  `json:"max_bytes,omitempty"`
+	}
+
+	// input: for the enforcement of the rules of the tenant by the job
+	RetentionEnforceRequestResource struct {
+		DryRun bool `json:"dry_run,omitempty"`
+	}
+
+	// output: retention rules of the tenant
+	RetentionRuleReplyResource struct {
+		Status bool               `json:"status"`
+		Count  int64              `json:"count"`
+		Data   []db.RetentionRule `json:"data,omitempty"`
+	}
+
+	// output: dry run report of the files to be removed by the rules
+	RetentionReportReplyResource struct {
+		Status bool                  `json:"status"`
+		Count  int64                 `json:"count"`
+		Bytes  int64                 `json:"bytes"`
+		Data   []store.RetentionItem `json:"data,omitempty"`
+	}
+)
@@ -0,0 +1,41 @@
+package rest
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// NewRetentionRuleRouter creates the router for admin API of the retention
+// rules of the tenants and their enforcement
+func NewRetentionRuleRouter(r *mux.Router) *mux.Router {
+	// Gets the retention rules of the tenant
+	r.HandleFunc("/api/v1/admin/tenants/{tenant}/retention",
+		ReadRetentionRules).
+		Methods("GET").
+		Name("ReadRetentionRules")
+
+	// Sets the retention rule of the category replacing the previous one
+	r.HandleFunc("/api/v1/admin/tenants/{tenant}/retention",
+		UpdateRetentionRule).
+		Methods("PUT").
+		Name("UpdateRetentionRule")
+
+	// Removes the retention rule of the category
+	r.HandleFunc("/api/v1/admin/tenants/{tenant}/retention",
+		DeleteRetentionRule).
+		Methods("DELETE").
+		Name("DeleteRetentionRule")
+
+	// Lists the files to be removed by the rules not removing them
+	r.HandleFunc("/api/v1/admin/tenants/{tenant}/retention/report",
+		ReadRetentionReport).
+		Methods("GET").
+		Name("ReadRetentionReport")
+
+	// Submits the job enforcing the rules of the tenant
+	r.HandleFunc("/api/v1/admin/tenants/{tenant}/retention/enforce",
+		EnforceRetentionRules).
+		Methods("POST").
+		Name("EnforceRetentionRules")
+
+	return r
+}
@@ -11,6 +11,15 @@ func NewRouter() *mux.Router {
 	r = NewSystemRouter(r)
 	r = NewMetricsRouter(r)	
 	r = NewFileStoreRouter(r)
+	r = NewDeviceGroupRouter(r)
+	r = NewTenantStoreRouter(r)
+	r = NewDownloadLinkRouter(r)
+	r = NewBundleRouter(r)
+	r = NewJobRouter(r)
+	r = NewRetentionRuleRouter(r)
+	r = NewLegalHoldRouter(r)
+	r = NewUsageRouter(r)
+	r = NewAuditRouter(r)
 
 	return r
 }
@@ -11,6 +11,7 @@ import (
 
 	. "fs/service/config"
 	"fs/service/metrics"
+	"fs/service/store"
 )
 
 // Server stores all needed fields for an API server
@@ -31,6 +32,7 @@ func NewServer() (*Server, error) {
 	router := NewRouter()
 	router.Use(loggingMiddleware)
 	router.Use(authMiddleware)
+	router.Use(auditMiddleware)
 	handler.UseHandler(router)
 	
 	// set up of main server config structure
@@ -42,6 +44,13 @@ func NewServer() (*Server, error) {
 		MaxHeaderBytes: 1 << 20,
 	}
 
+	// The content streamed in proxy mode takes as long as the link allows
+	if Setup.ProxyMode {
+		server.ReadHeaderTimeout = 10 * time.Second
+		server.ReadTimeout = Setup.ProxyTimeout
+		server.WriteTimeout = Setup.ProxyTimeout
+	}
+
 	// server waits on it if interrupted
 	shutdown := make(chan struct{})
 
@@ -53,6 +62,7 @@ func NewServer() (*Server, error) {
 		<-sigint
 		close(shutdown)
 		Log.Info("Server shutdown requested")
+		store.FlushAuditEvents()
 		os.Exit(0)
 	}()
 
@@ -0,0 +1,219 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+// Gets the store backend dedicated to the tenant
+func ReadTenantStore(w http.ResponseWriter, r *http.Request) {
+	tenant, err := pathVariableStr(r, "tenant", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable tenant",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable tenant: " + tenant)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	tss, count, err := dbrep.ReadTenantStore(tenant)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no store mapped to tenant: "+tenant,
+			http.StatusNotFound)
+		return
+	}
+
+	var reply = TenantStoreReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   tss,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Maps the tenant to the store backend. The kind and the credentials reference
+// must be configured by env variables as well as the key file in case of SSE-C
+// encryption, the new files of the tenant are stored
+// there while the existing ones remain in the backend recorded with them.
+// The presign limits of the tenant bound the validity of its urls.
+func UpdateTenantStore(w http.ResponseWriter, r *http.Request) {
+	tenant, err := pathVariableStr(r, "tenant", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable tenant",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable tenant: " + tenant)
+
+	payload, err := readPayload(r)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to read payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got text payload: " + string(payload))
+
+	var request TenantStoreRequestResource
+	err = json.Unmarshal(payload, &request)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Unable to decode json payload of the request",
+			http.StatusBadRequest)
+		return
+	}
+
+	if request.Kind == "" {
+		request.Kind = Setup.UseFileStore
+	}
+	request.Encryption = strings.ToUpper(request.Encryption)
+	err = store.ValidateBackend(store.Backend{
+		Kind:            request.Kind,
+		Bucket:          request.Bucket,
+		Credentials:     request.CredentialsRef,
+		Encryption:      request.Encryption,
+		EncryptionKeyID: request.KMSKeyID,
+	})
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Invalid store backend - "+err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
+	limits, err := ParsePresignLimits(request.PresignLimits)
+	if err != nil {
+		displayAppError(w, PayloadReadError,
+			"Invalid presign limits - "+err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+	request.PresignLimits = limits.String()
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	tss, count, err := dbrep.UpsertTenantStore(db.TenantStore{
+		TenantID:       tenant,
+		Kind:           request.Kind,
+		Bucket:         request.Bucket,
+		CredentialsRef: request.CredentialsRef,
+		Encryption:     request.Encryption,
+		KMSKeyID:       request.KMSKeyID,
+		PresignLimits:  request.PresignLimits,
+	})
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while writing to db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	var reply = TenantStoreReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   tss,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Removes the store backend mapping of the tenant
+func DeleteTenantStore(w http.ResponseWriter, r *http.Request) {
+	tenant, err := pathVariableStr(r, "tenant", true)
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			"Missing mandatory url path variable tenant",
+			http.StatusBadRequest)
+		return
+	}
+	Log.Debug("Got path variable tenant: " + tenant)
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	count, err := dbrep.DeleteTenantStore(tenant)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while deleting from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if count == 0 {
+		displayAppError(w, RepositoryReadError,
+			"Error no store mapped to tenant: "+tenant,
+			http.StatusNotFound)
+		return
+	}
+
+	var reply = DeleteReplyResource{
+		Status: true,
+		Count:  count,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
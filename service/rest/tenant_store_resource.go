@@ -0,0 +1,24 @@
+package rest
+
+import (
+	"fs/service/db"
+)
+
+type (
+	// input: for mapping the tenant to the dedicated store backend
+	TenantStoreRequestResource struct {
+		Kind           string `json:"kind,omitempty"`
+		Bucket         string `json:"bucket,omitempty"`
+		CredentialsRef string `json:"credentials_ref,omitempty"`
+		Encryption     string `json:"encryption,omitempty"`
+		KMSKeyID       string `json:"kms_key_id,omitempty"`
+		PresignLimits  string `json:"presign_limits,omitempty"`
+	}
+
+	// output: tenant store backend mapping
+	TenantStoreReplyResource struct {
+		Status bool             `json:"status"`
+		Count  int64            `json:"count"`
+		Data   []db.TenantStore `json:"data,omitempty"`
+	}
+)
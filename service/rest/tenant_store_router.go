@@ -0,0 +1,29 @@
+package rest
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// NewTenantStoreRouter creates the router for admin API mapping the tenants
+// to their dedicated store backends
+func NewTenantStoreRouter(r *mux.Router) *mux.Router {
+	// Gets the store backend mapped to the tenant
+	r.HandleFunc("/api/v1/admin/tenants/{tenant}/store",
+		ReadTenantStore).
+		Methods("GET").
+		Name("ReadTenantStore")
+
+	// Maps the tenant to the store backend replacing the previous mapping
+	r.HandleFunc("/api/v1/admin/tenants/{tenant}/store",
+		UpdateTenantStore).
+		Methods("PUT").
+		Name("UpdateTenantStore")
+
+	// Removes the mapping so the tenant uses the default store
+	r.HandleFunc("/api/v1/admin/tenants/{tenant}/store",
+		DeleteTenantStore).
+		Methods("DELETE").
+		Name("DeleteTenantStore")
+
+	return r
+}
@@ -0,0 +1,218 @@
+package rest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+const (
+	// The format of the months of the usage report
+	usageMonthLayout = "2006-01"
+)
+
+// Gets the usage of the tenant given by the path or the parameter, all of them
+// if not given, summed up by the dimensions of the by parameter, ex.
+// by=device,month, the heaviest first. The usage is limited to the months
+// from and to (exclusive) if given, ex. from=2024-01. The format parameter
+// csv exports the report as CSV instead of JSON. The summaries are refreshed
+// with the files changed since the last refresh first.
+func ReadUsage(w http.ResponseWriter, r *http.Request) {
+	tenant, _ := pathVariableStr(r, "tenant", false)
+	if tenant == "" {
+		tenant = r.FormValue("tenant")
+	}
+
+	var by []string
+	seen := make(map[string]bool)
+	if val := r.FormValue("by"); val != "" {
+		for _, dim := range strings.Split(val, ",") {
+			if _, ok := db.UsageDimensions[dim]; !ok {
+				displayAppError(w, UrlPathError,
+					"Invalid by - "+dim+", expecting: device, month, status",
+					http.StatusBadRequest)
+				return
+			}
+			if !seen[dim] {
+				seen[dim] = true
+				by = append(by, dim)
+			}
+		}
+	}
+
+	from, err := usageMonth(r, "from")
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+	to, err := usageMonth(r, "to")
+	if err != nil {
+		displayAppError(w, UrlPathError,
+			err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
+	var limit int
+	if val := r.FormValue("limit"); val != "" {
+		limit, err = strconv.Atoi(val)
+		if err != nil || limit < 0 {
+			displayAppError(w, UrlPathError,
+				"Invalid limit - "+val,
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	format := r.FormValue("format")
+	if format != "" && format != "json" && format != "csv" {
+		displayAppError(w, UrlPathError,
+			"Invalid format - "+format+", expecting: json, csv",
+			http.StatusBadRequest)
+		return
+	}
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	_, err = dbrep.RefreshUsage(false)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while refreshing usage summaries - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	urs, count, err := dbrep.ReadUsage(tenant, by, from, to, limit)
+	if err != nil {
+		displayAppError(w, RepositoryReadError,
+			"Error while reading from db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+
+	if format == "csv" {
+		writeUsageCsv(w, by, urs)
+		return
+	}
+
+	var reply = UsageReplyResource{
+		Status: true,
+		Count:  count,
+		Data:   urs,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// Refreshes the usage summaries with the files changed since the last refresh,
+// all of them are recomputed with the full parameter true
+func RefreshUsage(w http.ResponseWriter, r *http.Request) {
+	full := r.FormValue("full") == "true"
+
+	dbrep, err := db.NewRepository()
+	if err != nil {
+		displayAppError(w, RepositoryNewError,
+			"Error while creating db repository - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	defer dbrep.Close()
+
+	count, err := dbrep.RefreshUsage(full)
+	if err != nil {
+		displayAppError(w, RepositoryWriteError,
+			"Error while refreshing usage summaries - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	Log.Info("Refreshed usage summaries: " + fmt.Sprintf("%d", count) + " full: " + strconv.FormatBool(full))
+
+	var reply = UsageRefreshReplyResource{
+		Status: true,
+		Count:  count,
+	}
+
+	jstr, err := json.Marshal(&reply)
+	if err != nil {
+		displayAppError(w, EncoderJsonError,
+			"An error while marshalling data - "+err.Error(),
+			http.StatusInternalServerError)
+		return
+	}
+	logReply(jstr)
+
+	writeResponseWithJson(w, http.StatusOK, jstr)
+}
+
+// usageMonth is the first day of the month of the parameter if given
+func usageMonth(r *http.Request, label string) (*time.Time, error) {
+	val := r.FormValue(label)
+	if val == "" {
+		return nil, nil
+	}
+	month, err := time.Parse(usageMonthLayout, val)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s - %s, expecting: YYYY-MM", label, val)
+	}
+
+	return &month, nil
+}
+
+// writeUsageCsv writes the usage report as the CSV attachment with the
+// columns of the dimensions asked for
+func writeUsageCsv(w http.ResponseWriter, by []string, urs []db.UsageRow) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="usage.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	header := []string{"tenant_id"}
+	for _, dim := range by {
+		header = append(header, db.UsageDimensions[dim])
+	}
+	cw.Write(append(header, "count", "bytes", "stored_bytes"))
+
+	for _, ur := range urs {
+		record := []string{ur.TenantID}
+		for _, dim := range by {
+			switch dim {
+			case "device":
+				record = append(record, ur.DeviceID)
+			case "month":
+				record = append(record, ur.Month)
+			case "status":
+				record = append(record, ur.Status)
+			}
+		}
+		cw.Write(append(record, strconv.FormatInt(ur.Count, 10), strconv.FormatInt(ur.Bytes, 10), strconv.FormatInt(ur.StoredBytes, 10)))
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		Log.Error("Error while writing usage csv - " + err.Error())
+	}
+}
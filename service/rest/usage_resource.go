@@ -0,0 +1,20 @@
+package rest
+
+import (
+	"fs/service/db"
+)
+
+type (
+	// output: usage of the tenants summed up by the dimensions asked for
+	UsageReplyResource struct {
+		Status bool          `json:"status"`
+		Count  int64         `json:"count"`
+		Data   []db.UsageRow `json:"data,omitempty"`
+	}
+
+	// output: number of the summaries written by the refresh
+	UsageRefreshReplyResource struct {
+		Status bool  `json:"status"`
+		Count  int64 `json:"count"`
+	}
+)
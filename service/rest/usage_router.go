@@ -0,0 +1,29 @@
+package rest
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// NewUsageRouter creates the router for admin API of the usage reports
+// of the tenants
+func NewUsageRouter(r *mux.Router) *mux.Router {
+	// Gets the usage of all the tenants
+	r.HandleFunc("/api/v1/admin/usage",
+		ReadUsage).
+		Methods("GET").
+		Name("ReadUsage")
+
+	// Refreshes the usage summaries, all of them with full
+	r.HandleFunc("/api/v1/admin/usage/refresh",
+		RefreshUsage).
+		Methods("POST").
+		Name("RefreshUsage")
+
+	// Gets the usage of the tenant
+	r.HandleFunc("/api/v1/admin/tenants/{tenant}/usage",
+		ReadUsage).
+		Methods("GET").
+		Name("ReadTenantUsage")
+
+	return r
+}
@@ -0,0 +1,22 @@
+package store
+
+import (
+	"time"
+
+	"fs/service/db"
+)
+
+// presignedAccess describes the request the client has to send to the url:
+// the method, the headers signed into the url and the time the signature
+// expires. The form fields are given only for the POST policy.
+func presignedAccess(method, url string, headers, fields map[string]string, expiry time.Duration) db.FileAccess {
+	expiresAt := time.Now().UTC().Add(expiry)
+
+	return db.FileAccess{
+		Method:    method,
+		URL:       url,
+		Headers:   headers,
+		Fields:    fields,
+		ExpiresAt: &expiresAt,
+	}
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// The actions of the audit events
+const (
+	AuditActionCreate  = "create"
+	AuditActionPresign = "presign"
+	AuditActionAccess  = "access"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionAdmin   = "admin"
+)
+
+// The principal of the actions done by the service itself
+const AuditPrincipalRetention = "system:retention"
+
+const (
+	// auditQueueSize is the number of the requests whose events may wait
+	// for the writer, the request waits when the queue is full
+	auditQueueSize = 1024
+
+	// auditBatchSize is the number of the requests whose events are written
+	// by one insert at most
+	auditBatchSize = 64
+)
+
+// auditBatch are the events of one request, the flush comes with the channel
+// closed once the events queued before are written
+type auditBatch struct {
+	events []db.AuditEvent
+	done   chan struct{}
+}
+
+var (
+	auditOnce  sync.Once
+	auditQueue chan auditBatch
+)
+
+// RecordAuditEvents queues the events to be appended to the db repository and
+// to the json lines sink of the setup if any. They are written by the single
+// writer in batches, so the requests do not wait for the db. The audit never
+// fails the action, the errors are logged.
+func RecordAuditEvents(aes []db.AuditEvent) {
+	if len(aes) == 0 {
+		return
+	}
+	startAuditWriter()
+	auditQueue <- auditBatch{events: aes}
+}
+
+// FlushAuditEvents waits until the events recorded so far are written,
+// ex. before the service exits
+func FlushAuditEvents() {
+	startAuditWriter()
+	done := make(chan struct{})
+	auditQueue <- auditBatch{done: done}
+	<-done
+}
+
+// startAuditWriter starts the writer of the queued events once
+func startAuditWriter() {
+	auditOnce.Do(func() {
+		auditQueue = make(chan auditBatch, auditQueueSize)
+		go runAuditWriter(auditQueue)
+	})
+}
+
+// runAuditWriter writes the events queued meanwhile together. The repository
+// is opened once, it is opened again after any error.
+func runAuditWriter(queue chan auditBatch) {
+	var r *db.FileStoreRepositoryGORM
+	for batch := range queue {
+		aes := batch.events
+		var flushed []chan struct{}
+		if batch.done != nil {
+			flushed = append(flushed, batch.done)
+		}
+	more:
+		for i := 1; i < auditBatchSize; i++ {
+			select {
+			case next := <-queue:
+				aes = append(aes, next.events...)
+				if next.done != nil {
+					flushed = append(flushed, next.done)
+				}
+			default:
+				break more
+			}
+		}
+
+		if len(aes) > 0 {
+			r = writeAuditEvents(r, aes)
+		}
+		for _, done := range flushed {
+			close(done)
+		}
+	}
+}
+
+// writeAuditEvents appends the events to the db repository and to the sink,
+// it returns the repository to be used for the next events
+func writeAuditEvents(r *db.FileStoreRepositoryGORM, aes []db.AuditEvent) *db.FileStoreRepositoryGORM {
+	if r == nil {
+		rep, err := db.NewRepository()
+		if err != nil {
+			Log.Error("Error creating audit db repository - " + err.Error())
+		} else {
+			r = &rep
+		}
+	}
+	if r != nil {
+		_, err := r.CreateAuditEvents(aes)
+		if err != nil {
+			Log.Error("Error writing audit events - " + err.Error())
+			r.Close()
+			r = nil
+		}
+	}
+
+	if Setup.AuditSink != "" {
+		err := writeAuditSink(Setup.AuditSink, aes)
+		if err != nil {
+			Log.Error("Error writing audit sink " + Setup.AuditSink + " - " + err.Error())
+		}
+	}
+
+	return r
+}
+
+// writeAuditSink appends the events one json object per line to the file
+func writeAuditSink(path string, aes []db.AuditEvent) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, ae := range aes {
+		err = enc.Encode(ae)
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	return f.Close()
+}
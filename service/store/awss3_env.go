@@ -152,3 +152,83 @@ func logDefaultRepositoryEnv(bucket string,
 	Log.Debug(" Bucket: " + bucket)
 	Log.Debug("Presign: " + strconv.FormatInt(int64(presignInt), 10))
 }
+
+// LoadCredentialsRepositoryEnv Check existence of the credentials env variables
+// prefixed by the reference to the credentials. They override the credentials
+// of the store kind, ex. for a tenant with the dedicated store account.
+// Fields status:
+// - accessKeyID: mandatory, string, AWS credentials
+// - secretAccessKey: mandatory, string, AWS credentials
+func LoadCredentialsRepositoryEnv(prefix string) (err error,
+	accessKeyID, secretAccessKey string) {
+	var (
+		evar [2]string
+		eval [2]string
+	)
+
+	prefix = strings.ToUpper(prefix)
+	evar[0] = fmt.Sprintf("%s_ACCESS_KEY_ID", prefix)
+	evar[1] = fmt.Sprintf("%s_SECRET_ACCESS_KEY", prefix)
+
+	// Get the values of variables
+	for i := 0; i < 2; i++ {
+		eval[i] = os.Getenv(evar[i])
+	}
+
+	// Validate the values using their representation
+	if eval[0] == "" {
+		err = fmt.Errorf("Unknown access key id in %s: %s", evar[0], eval[0])
+	} else if eval[1] == "" {
+		err = fmt.Errorf("Unknown secret access key in %s: %s", evar[1], eval[1])
+	}
+	if err != nil {
+		return
+	}
+
+	accessKeyID, secretAccessKey = eval[0], eval[1]
+
+	return
+}
+
+// LoadEncryptionRepositoryEnv Check the server side encryption env variables
+// prefixed by the store kind. The encryption is optional.
+// Fields status:
+// - mode: optional, string, one of SSE-S3, SSE-KMS, SSE-C, none by default
+// - kmsKeyID: optional, string, KMS key for SSE-KMS, the default key of the bucket if not given
+// - keyFile: mandatory for SSE-C, string, file with the secret the tenant keys are derived from
+func LoadEncryptionRepositoryEnv(prefix string) (err error,
+	mode, kmsKeyID, keyFile string) {
+	var (
+		evar [3]string
+		eval [3]string
+	)
+
+	prefix = strings.ToUpper(prefix)
+	evar[0] = fmt.Sprintf("%s_ENCRYPTION", prefix)
+	evar[1] = fmt.Sprintf("%s_KMS_KEY_ID", prefix)
+	evar[2] = fmt.Sprintf("%s_SSEC_KEY_FILE", prefix)
+
+	// Get the values of variables
+	for i := 0; i < 3; i++ {
+		eval[i] = os.Getenv(evar[i])
+	}
+
+	// Validate the values using their representation
+	eval[0] = strings.ToUpper(eval[0])
+	if eval[0] == "NONE" {
+		eval[0] = ""
+	}
+	err = ValidateEncryption(eval[0])
+	if err != nil {
+		err = fmt.Errorf("Unknown encryption in %s: %s", evar[0], eval[0])
+	} else if eval[0] == EncryptionSSEC && eval[2] == "" {
+		err = fmt.Errorf("Unknown key file in %s: %s", evar[2], eval[2])
+	}
+	if err != nil {
+		return
+	}
+
+	mode, kmsKeyID, keyFile = eval[0], eval[1], eval[2]
+
+	return
+}
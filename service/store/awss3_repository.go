@@ -2,21 +2,28 @@ package store
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 
 	. "fs/service/config"
+	"fs/service/db"
 )
 
 // Stores necessary data to perform object operations
 type AWSS3Repository struct {
+	kind     string
+	layout   int
 	object   string
+	version  string
 	bucket   string
+	sse      serverSideEncryption
 	presign  time.Duration
 	filename string
 	session  *session.Session
@@ -25,44 +32,106 @@ type AWSS3Repository struct {
 
 // LocationOfBucket
 func (r AWSS3Repository) BucketLocation() (string, string) {
-	return Setup.UseFileStore, r.bucket
+	return r.kind, r.bucket
+}
+
+// ObjectName is the key of the object in the bucket
+func (r AWSS3Repository) ObjectName() string {
+	return r.object
+}
+
+// ObjectKeyLayout is the layout version of the object key
+func (r AWSS3Repository) ObjectKeyLayout() int {
+	return r.layout
+}
+
+// Encryption is the server side encryption mode of the object with the KMS key if any
+func (r AWSS3Repository) Encryption() (string, string) {
+	return r.sse.mode, r.sse.kmsKeyID
+}
+
+// PresignDuration is the validity of the presigned urls
+func (r AWSS3Repository) PresignDuration() time.Duration {
+	return r.presign
+}
+
+// WithPresignDuration is the copy of the repository signing the urls valid
+// for the given duration
+func (r AWSS3Repository) WithPresignDuration(d time.Duration) Repository {
+	r.presign = d
+	return r
 }
 
 // NewAWSS3RepositoryDefault creates a object with client connection to the datastore.
-// It uses default config AWS key env variables to make the connection.
-func NewAWSS3RepositoryDefault(tid, did, name string, id uint) (AWSS3Repository, error) {
-	object := objectKeyName(id, tid, did, name)
+// It uses default config AWS key env variables to make the connection unless
+// the backend refers to other credentials. The bucket of the existing object
+// is given, for the new one it is allocated in the bucket space.
+func NewAWSS3RepositoryDefault(be Backend, tid, did, name string, id uint) (AWSS3Repository, error) {
+	object, err := backendObjectKey(be, tid, did, name, id)
+	if err != nil {
+		return AWSS3Repository{}, err
+	}
+	sse, err := newServerSideEncryption(be, tid)
+	if err != nil {
+		return AWSS3Repository{}, err
+	}
 
 	// Load, validate, convert the config
-	err, bucket, presign := LoadDefaultRepositoryEnv(Setup.UseFileStore)
+	err, base, presign := LoadDefaultRepositoryEnv(be.Kind)
 	if err != nil {
 		return AWSS3Repository{},
 			fmt.Errorf("Error collecting env values: %s", err)
 	}
-	logDefaultRepositoryEnv(bucket, presign)
+	logDefaultRepositoryEnv(base, presign)
+	bucket := be.Bucket
+	if bucket == "" {
+		bucket = allocateBucket(base, tid, id)
+	}
+
+	// The default AWS credentials chain is used if not overriden
+	var creds *credentials.Credentials
+	if be.Credentials != "" {
+		err, accessKeyID, secretAccessKey := LoadCredentialsRepositoryEnv(be.Credentials)
+		if err != nil {
+			return AWSS3Repository{},
+				fmt.Errorf("Error collecting env values: %s", err)
+		}
+		creds = credentials.NewStaticCredentials(accessKeyID,
+			secretAccessKey,
+			"")
+	}
 
-	// Determine the log level of AWS connection
-	var logLevel *aws.LogLevelType
+	// Determine the log level of AWS connection, it is off unless debugging
+	logLevel := aws.LogLevel(aws.LogOff)
 	if Setup.LogLevel == "debug" {
 		logLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
 	}
 
-	// Create an AWS session to access the store
-	sess, err := session.NewSession(&aws.Config{
-		LogLevel: logLevel,
+	// Create an AWS session to access the store once for the kind and credentials
+	sess, svc, err := cachedSession(be.Kind+":"+be.Credentials, func() (*session.Session, *s3.S3, error) {
+		sess, err := session.NewSession(&aws.Config{
+			Credentials: creds,
+			LogLevel:    logLevel,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		Log.Debug("Created session: " + fmt.Sprintf("%+v", *sess))
+
+		// Create S3 service client
+		return sess, s3.New(sess, aws.NewConfig().WithLogLevel(*logLevel)), nil
 	})
 	if err != nil {
 		return AWSS3Repository{}, err
 	}
-	Log.Debug("Created session: " + fmt.Sprintf("%+v", *sess))
-
-	// Create S3 service client
-	svc := s3.New(sess, aws.NewConfig().WithLogLevel(*logLevel))
 
 	// Store values for later usage in creating store bucket objects
 	return AWSS3Repository{
+		kind:     be.Kind,
+		layout:   be.Layout,
 		object:   object,
 		bucket:   bucket,
+		sse:      sse,
 		presign:  time.Duration(presign) * time.Minute,
 		filename: name,
 		session:  sess,
@@ -73,17 +142,37 @@ func NewAWSS3RepositoryDefault(tid, did, name string, id uint) (AWSS3Repository,
 // NewAWSS3RepositoryGeneric creates a object with client connection to the datastore.
 // It uses the config for AWS key env variables to make the connection.
 // It is used for connection to the local testing store butt it is compatible
-// with the AWS S3 connection.
-func NewAWSS3RepositoryGeneric(tid, did, name string, id uint) (AWSS3Repository, error) {
-	object := objectKeyName(id, tid, did, name)
+// with the AWS S3 connection. The credentials of the store kind may be overriden
+// by the ones the backend refers to. The bucket of the existing object is given,
+// for the new one it is allocated in the bucket space.
+func NewAWSS3RepositoryGeneric(be Backend, tid, did, name string, id uint) (AWSS3Repository, error) {
+	object, err := backendObjectKey(be, tid, did, name, id)
+	if err != nil {
+		return AWSS3Repository{}, err
+	}
+	sse, err := newServerSideEncryption(be, tid)
+	if err != nil {
+		return AWSS3Repository{}, err
+	}
 
 	// Load, validate, convert the config
-	err, host, port, bucket, accessKeyID, secretAccessKey, region, secure, presign := LoadGenericRepositoryEnv(Setup.UseFileStore)
+	err, host, port, base, accessKeyID, secretAccessKey, region, secure, presign := LoadGenericRepositoryEnv(be.Kind)
 	if err != nil {
 		return AWSS3Repository{},
 			fmt.Errorf("Error collecting env values: %s", err)
 	}
-	logGenericRepositoryEnv(host, port, bucket, accessKeyID, secretAccessKey, region, secure, presign)
+	if be.Credentials != "" {
+		err, accessKeyID, secretAccessKey = LoadCredentialsRepositoryEnv(be.Credentials)
+		if err != nil {
+			return AWSS3Repository{},
+				fmt.Errorf("Error collecting env values: %s", err)
+		}
+	}
+	logGenericRepositoryEnv(host, port, base, accessKeyID, secretAccessKey, region, secure, presign)
+	bucket := be.Bucket
+	if bucket == "" {
+		bucket = allocateBucket(base, tid, id)
+	}
 
 	// All details from config file so that we can use minio for local testing
 	// but AWS may be accessed this way as well
@@ -105,32 +194,40 @@ func NewAWSS3RepositoryGeneric(tid, did, name string, id uint) (AWSS3Repository,
 
 	endpoint := fmt.Sprintf("%s://%s:%s", protocol, host, port)
 
-	// Determine the log level of AWS connection
-	var logLevel *aws.LogLevelType
+	// Determine the log level of AWS connection, it is off unless debugging
+	logLevel := aws.LogLevel(aws.LogOff)
 	if Setup.LogLevel == "debug" {
 		logLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
 	}
 
-	// Create an AWS session to access the store
-	sess, err := session.NewSession(&aws.Config{
-		Endpoint:         aws.String(endpoint),
-		Region:           aws.String(region),
-		Credentials:      creds,
-		S3ForcePathStyle: aws.Bool(true),
-		LogLevel:         logLevel,
+	// Create an AWS session to access the store once for the kind and credentials
+	sess, svc, err := cachedSession(be.Kind+":"+be.Credentials, func() (*session.Session, *s3.S3, error) {
+		sess, err := session.NewSession(&aws.Config{
+			Endpoint:         aws.String(endpoint),
+			Region:           aws.String(region),
+			Credentials:      creds,
+			S3ForcePathStyle: aws.Bool(true),
+			LogLevel:         logLevel,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		Log.Debug("Created session: " + fmt.Sprintf("%+v", sess))
+
+		// Create S3 service client
+		return sess, s3.New(sess, aws.NewConfig().WithLogLevel(*logLevel)), nil
 	})
 	if err != nil {
 		return AWSS3Repository{}, err
 	}
-	Log.Debug("Created session: " + fmt.Sprintf("%+v", sess))
-
-	// Create S3 service client
-	svc := s3.New(sess, aws.NewConfig().WithLogLevel(*logLevel))
 
 	// Store values for later usage in creating store bucket objects
 	return AWSS3Repository{
+		kind:     be.Kind,
+		layout:   be.Layout,
 		object:   object,
 		bucket:   bucket,
+		sse:      sse,
 		presign:  time.Duration(presign) * time.Minute,
 		filename: name,
 		session:  sess,
@@ -138,64 +235,202 @@ func NewAWSS3RepositoryGeneric(tid, did, name string, id uint) (AWSS3Repository,
 	}, nil
 }
 
-// FileObjectPresignedGetURL provides url for GET mode acceess to the existing object.
-// It mey be used to implement Read operation on the object. The precondition is
-// that the object exists.
-func (r AWSS3Repository) FileObjectPresignedGetURL(cksum string, size int64) (string, error) {
-	// GET method to be used
-	req, _ := r.service.GetObjectRequest(&s3.GetObjectInput{
-		Bucket:         aws.String(r.bucket),
-		Key:            aws.String(r.object),
+// AssureBucketExist checks bucket existence and creates bucket if it does not exist.
+// The buckets once checked are remembered to avoid the round trip on each request.
+func (r AWSS3Repository) AssureBucketExist() error {
+	if _, ok := knownBuckets.Load(r.kind + ":" + r.bucket); ok {
+		return nil
+	}
+	Log.Debug("Checking bucket: " + r.bucket)
+
+	_, err := r.service.HeadBucket(&s3.HeadBucketInput{
+		Bucket: aws.String(r.bucket),
 	})
+	if err != nil {
+		Log.Debug("Bucket does not exist: " + r.bucket + " - " + err.Error())
+		_, err = r.service.CreateBucket(&s3.CreateBucketInput{
+			Bucket:                     aws.String(r.bucket),
+			CreateBucketConfiguration:  bucketConfiguration(aws.StringValue(r.session.Config.Region)),
+			ObjectLockEnabledForBucket: aws.Bool(Setup.ObjectLock),
+		})
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != s3.ErrCodeBucketAlreadyOwnedByYou {
+				return fmt.Errorf("Failed to create bucket %s: %s", r.bucket, err)
+			}
+		}
+		Log.Debug("Created bucket: " + r.bucket)
+
+		// The bucket with the object lock is versioned anyway
+		if Setup.ObjectVersioning && !Setup.ObjectLock {
+			_, err = r.service.PutBucketVersioning(&s3.PutBucketVersioningInput{
+				Bucket: aws.String(r.bucket),
+				VersioningConfiguration: &s3.VersioningConfiguration{
+					Status: aws.String(s3.BucketVersioningStatusEnabled),
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("Failed to enable versioning of bucket %s: %s", r.bucket, err)
+			}
+			Log.Debug("Enabled versioning of bucket: " + r.bucket)
+		}
+	}
+	knownBuckets.Store(r.kind+":"+r.bucket, true)
+
+	return nil
+}
+
+// bucketConfiguration places the bucket in the region of the store, the
+// default us-east-1 is the one not to be given as the location constraint
+func bucketConfiguration(region string) *s3.CreateBucketConfiguration {
+	if region == "" || region == "us-east-1" {
+		return nil
+	}
+
+	return &s3.CreateBucketConfiguration{
+		LocationConstraint: aws.String(region),
+	}
+}
+
+// FileObjectPresignedGetURL provides url for GET mode acceess to the existing object
+// with the headers signed into it. The response headers may be overriden by
+// the validated ones. It mey be used to implement Read operation on the object.
+// The precondition is that the object exists.
+func (r AWSS3Repository) FileObjectPresignedGetURL(cksum string, size int64, response ResponseOverrides) (db.FileAccess, error) {
+	// GET method to be used
+	params := responseParams(response, r.filename)
+	input := &s3.GetObjectInput{
+		Bucket:                     aws.String(r.bucket),
+		Key:                        aws.String(r.object),
+		ResponseContentDisposition: aws.String(params.Get("response-content-disposition")),
+	}
+	if r.version != "" {
+		input.VersionId = aws.String(r.version)
+	}
+	if params.Has("response-content-type") {
+		input.ResponseContentType = aws.String(params.Get("response-content-type"))
+	}
+	if params.Has("response-cache-control") {
+		input.ResponseCacheControl = aws.String(params.Get("response-cache-control"))
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = r.sse.customer()
+	req, _ := r.service.GetObjectRequest(input)
 
 	// Get presinged URL of create empty object
-	url, err := req.Presign(r.presign)
+	url, header, err := req.PresignRequest(r.presign)
 	if err != nil {
-		return "", fmt.Errorf("Failed to sign request: %s", err)
+		return db.FileAccess{}, fmt.Errorf("Failed to sign request: %s", err)
 	}
 	Log.Debug("Created AWS GET presigned request url: " + url)
 
-	return url, nil
+	return presignedAccess(http.MethodGet, url, signedHeaders(header), nil, r.presign), nil
 }
 
-// FileObjectPresignedHeadURL provides url for HEAD mode acceess to the existing object.
-// It mey be used to implement Read operation on the object. The precondition is
-// that the object exists.
-func (r AWSS3Repository) FileObjectPresignedHeadURL(cksum string, size int64) (string, error) {
+// FileObjectPresignedHeadURL provides url for HEAD mode acceess to the existing object
+// with the headers signed into it. It mey be used to implement Read operation
+// on the object. The precondition is that the object exists.
+func (r AWSS3Repository) FileObjectPresignedHeadURL(cksum string, size int64) (db.FileAccess, error) {
 	// HEAD method to be used
-	req, _ := r.service.HeadObjectRequest(&s3.HeadObjectInput{
+	input := &s3.HeadObjectInput{
 		Bucket:         aws.String(r.bucket),
 		Key:            aws.String(r.object),
-	})
+	}
+	if r.version != "" {
+		input.VersionId = aws.String(r.version)
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = r.sse.customer()
+	req, _ := r.service.HeadObjectRequest(input)
 
 	// Get presinged URL of create empty object
-	url, err := req.Presign(r.presign)
+	url, header, err := req.PresignRequest(r.presign)
 	if err != nil {
-		return "", fmt.Errorf("Failed to sign request: %s", err)
+		return db.FileAccess{}, fmt.Errorf("Failed to sign request: %s", err)
 	}
 	Log.Debug("Created AWS HEAD presigned request url: " + url)
 
-	return url, nil
+	return presignedAccess(http.MethodHead, url, signedHeaders(header), nil, r.presign), nil
 }
 
-// FileObjectPresignedPutURL provides url for GET mode acceess to the existing object.
-// It may be used to implement Update operation on the object. The precondition is
-// that the object exists.
-func (r AWSS3Repository) FileObjectPresignedPutURL(cksum string, size int64) (string, error) {
+// FileObjectPresignedPutURL provides url for PUT mode acceess to the object with
+// the headers signed into it. The content type declared by the client is stored
+// with the object, the metadata of the file as the user metadata and tags of
+// the object. It may be used to implement Create or Update operation on the object.
+func (r AWSS3Repository) FileObjectPresignedPutURL(cksumType, cksum string, size int64, contentType string, metadata map[string]string) (db.FileAccess, error) {
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(r.bucket),
+		Key:           aws.String(r.object),
+		ContentLength: aws.Int64(size),
+		Metadata:      objectMetadata(metadata),
+		Tagging:       objectTagging(metadata),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	input.ServerSideEncryption, input.SSEKMSKeyId = r.sse.serverSideEncryption()
+	input.SSECustomerAlgorithm, input.SSECustomerKey = r.sse.customer()
+
+	// The store rejects the upload if the content does not match the checksum
+	if cksum != "" {
+		sum, err := ChecksumBase64(cksumType, cksum)
+		if err != nil {
+			return db.FileAccess{}, err
+		}
+		switch strings.ToUpper(cksumType) {
+		case "SHA256":
+			input.ChecksumSHA256 = aws.String(sum)
+		case "SHA1":
+			input.ChecksumSHA1 = aws.String(sum)
+		case "CRC32C":
+			input.ChecksumCRC32C = aws.String(sum)
+		case "CRC32":
+			input.ChecksumCRC32 = aws.String(sum)
+		case "MD5":
+			input.ContentMD5 = aws.String(sum)
+		}
+	}
+
 	// PUT method to be used
-	req, _ := r.service.PutObjectRequest(&s3.PutObjectInput{
-		Bucket:         aws.String(r.bucket),
-		Key:            aws.String(r.object),
-		ChecksumSHA256: aws.String(cksum),
-		ContentLength:  aws.Int64(size),
-	})
+	req, _ := r.service.PutObjectRequest(input)
 
 	// Get presinged URL of create empty object
-	url, err := req.Presign(r.presign)
+	url, header, err := req.PresignRequest(r.presign)
 	if err != nil {
-		return "", fmt.Errorf("Failed to sign request: %s", err)
+		return db.FileAccess{}, fmt.Errorf("Failed to sign request: %s", err)
 	}
 	Log.Debug("Created AWS PUT presigned request url: " + url)
 
-	return url, nil
+	return presignedAccess(http.MethodPut, url, signedHeaders(header), nil, r.presign), nil
+}
+
+// FileObjectChecksum provides the checksum of given algorithm of the existing
+// object as calculated by the store on upload, base64 encoded.
+func (r AWSS3Repository) FileObjectChecksum(cksumType string) (string, error) {
+	input := &s3.HeadObjectInput{
+		Bucket:       aws.String(r.bucket),
+		Key:          aws.String(r.object),
+		ChecksumMode: aws.String(s3.ChecksumModeEnabled),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = r.sse.customer()
+	out, err := r.service.HeadObject(input)
+	if err != nil {
+		return "", fmt.Errorf("Failed to head object: %s", err)
+	}
+
+	var sum string
+	switch strings.ToUpper(cksumType) {
+	case "SHA256":
+		sum = aws.StringValue(out.ChecksumSHA256)
+	case "SHA1":
+		sum = aws.StringValue(out.ChecksumSHA1)
+	case "CRC32C":
+		sum = aws.StringValue(out.ChecksumCRC32C)
+	case "CRC32":
+		sum = aws.StringValue(out.ChecksumCRC32)
+	case "MD5":
+		sum = etagChecksumMD5(aws.StringValue(out.ETag))
+	default:
+		return "", fmt.Errorf("Invalid checksum type: %s", cksumType)
+	}
+	Log.Debug("Got AWS object checksum: " + cksumType + " " + sum)
+
+	return sum, nil
 }
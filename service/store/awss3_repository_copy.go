@@ -0,0 +1,154 @@
+package store
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	. "fs/service/config"
+)
+
+// FileObjectCopy copies the object to the new key in the same bucket keeping
+// the checksum of the given algorithm, the encryption, the content type and the metadata.
+// The object larger then the single copy limit is copied in parts. The version
+// of the object is copied if the repository is pinned to one.
+func (r AWSS3Repository) FileObjectCopy(object, cksumType string, size int64, contentType string, metadata map[string]string) error {
+	source := url.PathEscape(r.bucket + "/" + r.object)
+	if r.version != "" {
+		source += "?versionId=" + url.QueryEscape(r.version)
+	}
+	algorithm := checksumAlgorithm(cksumType)
+
+	if size <= MultipartMaxPartSize {
+		input := &s3.CopyObjectInput{
+			Bucket:     aws.String(r.bucket),
+			Key:        aws.String(object),
+			CopySource: aws.String(source),
+		}
+		if algorithm != "" {
+			input.ChecksumAlgorithm = aws.String(algorithm)
+		}
+		input.ServerSideEncryption, input.SSEKMSKeyId = r.sse.serverSideEncryption()
+		input.SSECustomerAlgorithm, input.SSECustomerKey = r.sse.customer()
+		input.CopySourceSSECustomerAlgorithm, input.CopySourceSSECustomerKey = r.sse.customer()
+		_, err := r.service.CopyObject(input)
+		if err != nil {
+			return fmt.Errorf("Failed to copy object: %s", err)
+		}
+		Log.Debug("Copied AWS object: " + r.object + " to: " + object)
+
+		return nil
+	}
+
+	sizes, err := MultipartPartSizes(size, Setup.MultipartPartSize)
+	if err != nil {
+		return err
+	}
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:   aws.String(r.bucket),
+		Key:      aws.String(object),
+		Metadata: objectMetadata(metadata),
+		Tagging:  objectTagging(metadata),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	if algorithm != "" {
+		input.ChecksumAlgorithm = aws.String(algorithm)
+	}
+	input.ServerSideEncryption, input.SSEKMSKeyId = r.sse.serverSideEncryption()
+	input.SSECustomerAlgorithm, input.SSECustomerKey = r.sse.customer()
+	out, err := r.service.CreateMultipartUpload(input)
+	if err != nil {
+		return fmt.Errorf("Failed to create multipart upload: %s", err)
+	}
+
+	completed := make([]*s3.CompletedPart, len(sizes))
+	var offset int64
+	for i, partSize := range sizes {
+		partInput := &s3.UploadPartCopyInput{
+			Bucket:          aws.String(r.bucket),
+			Key:             aws.String(object),
+			UploadId:        out.UploadId,
+			PartNumber:      aws.Int64(int64(i + 1)),
+			CopySource:      aws.String(source),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+partSize-1)),
+		}
+		partInput.SSECustomerAlgorithm, partInput.SSECustomerKey = r.sse.customer()
+		partInput.CopySourceSSECustomerAlgorithm, partInput.CopySourceSSECustomerKey = r.sse.customer()
+		part, err := r.service.UploadPartCopy(partInput)
+		if err != nil {
+			r.service.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(r.bucket),
+				Key:      aws.String(object),
+				UploadId: out.UploadId,
+			})
+			return fmt.Errorf("Failed to copy object part %d: %s", i+1, err)
+		}
+		completed[i] = &s3.CompletedPart{
+			ETag:           part.CopyPartResult.ETag,
+			ChecksumCRC32:  part.CopyPartResult.ChecksumCRC32,
+			ChecksumCRC32C: part.CopyPartResult.ChecksumCRC32C,
+			ChecksumSHA1:   part.CopyPartResult.ChecksumSHA1,
+			ChecksumSHA256: part.CopyPartResult.ChecksumSHA256,
+			PartNumber:     aws.Int64(int64(i + 1)),
+		}
+		offset += partSize
+	}
+
+	_, err = r.service.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(r.bucket),
+		Key:      aws.String(object),
+		UploadId: out.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: completed,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to complete multipart copy: %s", err)
+	}
+	Log.Debug("Copied AWS object in parts: " + r.object + " to: " + object)
+
+	return nil
+}
+
+// FileObjectDelete removes the object from the bucket. The version of the
+// object is removed for good if the repository is pinned to one, otherwise
+// the versioned bucket just adds the delete marker.
+func (r AWSS3Repository) FileObjectDelete() error {
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(r.object),
+	}
+	if r.version != "" {
+		input.VersionId = aws.String(r.version)
+	}
+	_, err := r.service.DeleteObject(input)
+	if err != nil {
+		return fmt.Errorf("Failed to delete object: %s", err)
+	}
+	Log.Debug("Deleted AWS object: " + r.object)
+
+	return nil
+}
+
+// checksumAlgorithm is the name of the additional checksum algorithm
+// computed by the store, the MD5 is always there as the etag
+func checksumAlgorithm(cksumType string) string {
+	switch strings.ToUpper(cksumType) {
+	case "SHA256":
+		return s3.ChecksumAlgorithmSha256
+	case "SHA1":
+		return s3.ChecksumAlgorithmSha1
+	case "CRC32C":
+		return s3.ChecksumAlgorithmCrc32c
+	case "CRC32":
+		return s3.ChecksumAlgorithmCrc32
+	}
+
+	return ""
+}
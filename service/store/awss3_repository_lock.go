@@ -0,0 +1,43 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	. "fs/service/config"
+)
+
+// FileObjectLegalHold sets or clears the object lock legal hold of the object
+// so that the store itself refuses to delete it. The hold is set on the version
+// of the object if the repository is pinned to one. The bucket created without
+// the object lock does not support it, which is reported as such.
+func (r AWSS3Repository) FileObjectLegalHold(on bool) error {
+	status := s3.ObjectLockLegalHoldStatusOff
+	if on {
+		status = s3.ObjectLockLegalHoldStatusOn
+	}
+
+	input := &s3.PutObjectLegalHoldInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(r.object),
+		LegalHold: &s3.ObjectLockLegalHold{
+			Status: aws.String(status),
+		},
+	}
+	if r.version != "" {
+		input.VersionId = aws.String(r.version)
+	}
+	_, err := r.service.PutObjectLegalHold(input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == "InvalidRequest" || aerr.Code() == "ObjectLockConfigurationNotFoundError") {
+			return ErrObjectLockUnsupported
+		}
+		return fmt.Errorf("Failed to set legal hold: %s", err)
+	}
+	Log.Debug("Set legal hold " + status + " of AWS object: " + r.object)
+
+	return nil
+}
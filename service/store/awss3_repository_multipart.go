@@ -0,0 +1,129 @@
+package store
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// FileObjectMultipartCreate starts the multipart upload of the object with the content
+// type and the metadata of the file returning the upload id to be used in the part uploads.
+func (r AWSS3Repository) FileObjectMultipartCreate(contentType string, metadata map[string]string) (string, error) {
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:   aws.String(r.bucket),
+		Key:      aws.String(r.object),
+		Metadata: objectMetadata(metadata),
+		Tagging:  objectTagging(metadata),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	input.ServerSideEncryption, input.SSEKMSKeyId = r.sse.serverSideEncryption()
+	input.SSECustomerAlgorithm, input.SSECustomerKey = r.sse.customer()
+	out, err := r.service.CreateMultipartUpload(input)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create multipart upload: %s", err)
+	}
+	Log.Debug("Created AWS multipart upload: " + *out.UploadId)
+
+	return *out.UploadId, nil
+}
+
+// FileObjectPresignedPartURL provides url for PUT of a single part of the object
+// in the multipart upload with the headers signed into it. The part must have
+// exactly the given size.
+func (r AWSS3Repository) FileObjectPresignedPartURL(uploadId string, part, size int64) (db.FileAccess, error) {
+	// PUT method to be used
+	input := &s3.UploadPartInput{
+		Bucket:        aws.String(r.bucket),
+		Key:           aws.String(r.object),
+		UploadId:      aws.String(uploadId),
+		PartNumber:    aws.Int64(part),
+		ContentLength: aws.Int64(size),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = r.sse.customer()
+	req, _ := r.service.UploadPartRequest(input)
+
+	// Get presinged URL of the part upload
+	url, header, err := req.PresignRequest(r.presign)
+	if err != nil {
+		return db.FileAccess{}, fmt.Errorf("Failed to sign request: %s", err)
+	}
+	Log.Debug("Created AWS PUT part presigned request url: " + url)
+
+	return presignedAccess(http.MethodPut, url, signedHeaders(header), nil, r.presign), nil
+}
+
+// FileObjectMultipartComplete assembles the object from the uploaded parts
+// identified by the etags returned by the store on the part uploads.
+func (r AWSS3Repository) FileObjectMultipartComplete(uploadId string, parts []db.FilePart) error {
+	completed := make([]*s3.CompletedPart, len(parts))
+	for i, part := range parts {
+		completed[i] = &s3.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int64(part.Number),
+		}
+	}
+
+	_, err := r.service.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(r.bucket),
+		Key:      aws.String(r.object),
+		UploadId: aws.String(uploadId),
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: completed,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to complete multipart upload: %s", err)
+	}
+	Log.Debug("Completed AWS multipart upload: " + uploadId)
+
+	return nil
+}
+
+// FileObjectMultipartAbort cancels the multipart upload releasing
+// the storage of the parts uploaded so far.
+func (r AWSS3Repository) FileObjectMultipartAbort(uploadId string) error {
+	_, err := r.service.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(r.bucket),
+		Key:      aws.String(r.object),
+		UploadId: aws.String(uploadId),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to abort multipart upload: %s", err)
+	}
+	Log.Debug("Aborted AWS multipart upload: " + uploadId)
+
+	return nil
+}
+
+// FileObjectMultipartParts lists the parts already received by the store
+// in the multipart upload with their sizes and etags.
+func (r AWSS3Repository) FileObjectMultipartParts(uploadId string) ([]db.FilePart, error) {
+	var parts []db.FilePart
+	err := r.service.ListPartsPages(&s3.ListPartsInput{
+		Bucket:   aws.String(r.bucket),
+		Key:      aws.String(r.object),
+		UploadId: aws.String(uploadId),
+	}, func(page *s3.ListPartsOutput, lastPage bool) bool {
+		for _, part := range page.Parts {
+			parts = append(parts, db.FilePart{
+				Number: aws.Int64Value(part.PartNumber),
+				Size:   aws.Int64Value(part.Size),
+				ETag:   aws.StringValue(part.ETag),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list multipart upload parts: %s", err)
+	}
+	Log.Debug("Listed AWS multipart upload: " + uploadId + fmt.Sprintf(" with %d parts", len(parts)))
+
+	return parts, nil
+}
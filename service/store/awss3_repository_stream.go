@@ -0,0 +1,63 @@
+package store
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	. "fs/service/config"
+)
+
+// FileObjectReader opens the content of the existing object to be read by
+// the service itself, ex. to be put into the bundle. It returns the size
+// of the content, the reader must be closed by the caller.
+func (r AWSS3Repository) FileObjectReader() (io.ReadCloser, int64, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(r.object),
+	}
+	if r.version != "" {
+		input.VersionId = aws.String(r.version)
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = r.sse.customer()
+	out, err := r.service.GetObject(input)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Failed to get object: %s", err)
+	}
+	Log.Debug("Opened AWS object: " + r.object)
+
+	return out.Body, aws.Int64Value(out.ContentLength), nil
+}
+
+// FileObjectUpload stores the content of unknown size produced by the service
+// itself, ex. the bundle. It is uploaded in parts of the configured size one
+// at a time so that only the part being sent is held in memory.
+func (r AWSS3Repository) FileObjectUpload(body io.Reader, contentType string, metadata map[string]string) error {
+	input := &s3manager.UploadInput{
+		Bucket:   aws.String(r.bucket),
+		Key:      aws.String(r.object),
+		Body:     body,
+		Metadata: objectMetadata(metadata),
+		Tagging:  objectTagging(metadata),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	input.ServerSideEncryption, input.SSEKMSKeyId = r.sse.serverSideEncryption()
+	input.SSECustomerAlgorithm, input.SSECustomerKey = r.sse.customer()
+
+	uploader := s3manager.NewUploaderWithClient(r.service, func(u *s3manager.Uploader) {
+		u.PartSize = Setup.MultipartPartSize
+		u.Concurrency = 1
+	})
+	_, err := uploader.Upload(input)
+	if err != nil {
+		return fmt.Errorf("Failed to upload object: %s", err)
+	}
+	Log.Debug("Uploaded AWS object: " + r.object)
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	. "fs/service/config"
+)
+
+// objectVersionNull is the version id reported by the bucket with
+// no versioning enabled
+const objectVersionNull = "null"
+
+// WithVersion is the copy of the repository reading the given version of
+// the object rather then the latest one, the empty version means the latest
+func (r AWSS3Repository) WithVersion(version string) Repository {
+	r.version = version
+	return r
+}
+
+// FileObjectVersion provides the version id of the latest object as assigned
+// by the versioned bucket, the empty one if the bucket is not versioned
+func (r AWSS3Repository) FileObjectVersion() (string, error) {
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(r.object),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = r.sse.customer()
+	out, err := r.service.HeadObject(input)
+	if err != nil {
+		return "", fmt.Errorf("Failed to head object: %s", err)
+	}
+
+	version := aws.StringValue(out.VersionId)
+	if version == objectVersionNull {
+		version = ""
+	}
+	Log.Debug("Got AWS object version: " + r.object + " " + version)
+
+	return version, nil
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+var (
+	// knownSessions caches the sessions of the store kinds by the credentials,
+	// the session and its client are safe for the concurrent use
+	knownSessions sync.Map
+)
+
+// awss3Session is the session with the S3 client of the store kind and the
+// credentials, created once and reused by the repositories of all the files
+type awss3Session struct {
+	session *session.Session
+	service *s3.S3
+}
+
+// cachedSession gets the session of the key, the one created by the function
+// on first use. The changed credentials are picked up on restart.
+func cachedSession(key string, create func() (*session.Session, *s3.S3, error)) (*session.Session, *s3.S3, error) {
+	if v, ok := knownSessions.Load(key); ok {
+		s := v.(awss3Session)
+		return s.session, s.service, nil
+	}
+
+	sess, svc, err := create()
+	if err != nil {
+		return nil, nil, err
+	}
+	v, _ := knownSessions.LoadOrStore(key, awss3Session{sess, svc})
+	s := v.(awss3Session)
+
+	return s.session, s.service, nil
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// Backend identifies the store holding the object: the kind of the configured
+// store, the bucket and optional reference to the credentials overriding
+// the ones of the store kind. The empty bucket means it is to be allocated
+// in the bucket space of the store kind. The empty object means the new one
+// to be named with the layout and encrypted with the mode of the tenant
+// or of the store kind if not given. The UUID is the public id of the file
+// naming its new object.
+type Backend struct {
+	Kind            string
+	Bucket          string
+	Credentials     string
+	Object          string
+	UUID            string
+	Layout          int
+	Encryption      string
+	EncryptionKeyID string
+}
+
+// DefaultBackend is the globally configured store
+func DefaultBackend() Backend {
+	return Backend{
+		Kind:   Setup.UseFileStore,
+		Layout: Setup.ObjectKeyLayout,
+	}
+}
+
+// TenantBackend is the store dedicated to the tenant by the mapping if any,
+// otherwise the default one
+func TenantBackend(tss []db.TenantStore) Backend {
+	if len(tss) == 0 {
+		return DefaultBackend()
+	}
+
+	return Backend{
+		Kind:            tss[0].Kind,
+		Bucket:          tss[0].Bucket,
+		Credentials:     tss[0].CredentialsRef,
+		Layout:          Setup.ObjectKeyLayout,
+		Encryption:      tss[0].Encryption,
+		EncryptionKeyID: tss[0].KMSKeyID,
+	}
+}
+
+// FileBackend is the store recorded with the file so that it stays reachable
+// when the tenant mapping or the key layout changes. The files recorded before
+// the backend was tracked are in the default store with the untracked layout.
+func FileBackend(fs db.FileStore) Backend {
+	be := Backend{
+		Kind:            fs.Location,
+		Bucket:          fs.Bucket,
+		Credentials:     fs.Credentials,
+		Object:          fs.Object,
+		Layout:          fs.KeyLayout,
+		Encryption:      fs.Encryption,
+		EncryptionKeyID: fs.EncryptionKeyID,
+	}
+	if be.Kind == "" {
+		be.Kind = Setup.UseFileStore
+	}
+	if fs.UUID != nil {
+		be.UUID = *fs.UUID
+	}
+
+	return be
+}
+
+// ValidateBackend checks that the store kind and the credentials reference
+// are configured so the repository may be created
+func ValidateBackend(be Backend) error {
+	if len(be.Kind) < 5 || be.Kind[0:5] != "awss3" {
+		return fmt.Errorf("Invalid kind of repository: %s", be.Kind)
+	}
+
+	var err error
+	if be.Kind == "awss3" {
+		err, _, _ = LoadDefaultRepositoryEnv(be.Kind)
+	} else {
+		err, _, _, _, _, _, _, _, _ = LoadGenericRepositoryEnv(be.Kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	if be.Credentials != "" {
+		err, _, _ = LoadCredentialsRepositoryEnv(be.Credentials)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ValidateEncryption(be.Encryption)
+	if err != nil {
+		return err
+	}
+	if be.Encryption == EncryptionSSEC {
+		err, _, _, keyFile := LoadEncryptionRepositoryEnv(be.Kind)
+		if err != nil {
+			return err
+		}
+		if keyFile == "" {
+			return fmt.Errorf("Missing key file of encryption %s in store: %s", be.Encryption, be.Kind)
+		}
+	}
+
+	return nil
+}
+
+// String is the printable form used in logs
+func (be Backend) String() string {
+	return strings.Join([]string{be.Kind, be.Bucket, be.Credentials, be.Object}, ":")
+}
+
+// backendObjectKey is the key of the existing object recorded with the file,
+// the new object is named with the layout of the backend
+func backendObjectKey(be Backend, tid, did, name string, id uint) (string, error) {
+	if be.Object != "" {
+		return be.Object, nil
+	}
+
+	return ObjectKey(be.Layout, id, be.UUID, tid, did, name)
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	. "fs/service/config"
+)
+
+var (
+	// knownBuckets caches the buckets already checked or created in the store
+	knownBuckets sync.Map
+)
+
+// BucketName allocates the bucket for the object spreading the objects evenly
+// over the space of buckets by a stable hash of the shard key. The buckets are
+// named by the base bucket name with the index suffix. The space of one bucket
+// is the base bucket itself.
+func BucketName(base string, space uint, shardKey string) string {
+	if space <= 1 {
+		return base
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(shardKey))
+
+	return fmt.Sprintf("%s-%d", base, uint(h.Sum32())%space)
+}
+
+// allocateBucket chooses the bucket of the new object by the configured
+// shard key: tenant keeps all tenant objects in one bucket, id spreads them.
+func allocateBucket(base, tid string, id uint) string {
+	shardKey := tid
+	if Setup.BucketShardKey == "id" {
+		shardKey = fmt.Sprintf("%d", id)
+	}
+	bucket := BucketName(base, Setup.BucketSpaceSize, shardKey)
+	Log.Debug("Allocated bucket: " + bucket + " for shard key: " + shardKey)
+
+	return bucket
+}
@@ -0,0 +1,348 @@
+package store
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// The formats of the bundle archive
+const (
+	BundleFormatZip   = "zip"
+	BundleFormatTarGz = "tar.gz"
+)
+
+// BundleCategory is the metadata category of the files holding the bundles,
+// they are not put into the other bundles
+const BundleCategory = "bundle"
+
+// The states of the bundle
+const (
+	BundleStatePending = "P"
+	BundleStateRunning = "R"
+	BundleStateDone    = "D"
+	BundleStateFailed  = "F"
+)
+
+// bundleContentTypes are the content types of the archives of the formats
+var bundleContentTypes = map[string]string{
+	BundleFormatZip:   "application/zip",
+	BundleFormatTarGz: "application/gzip",
+}
+
+// BundleEntry is the file put into the bundle, its content is opened
+// only when it is written so that one file is read at a time
+type BundleEntry struct {
+	Name    string
+	ModTime time.Time
+	Open    func() (io.ReadCloser, int64, error)
+}
+
+// ValidateBundleFormat checks the format of the bundle is supported
+func ValidateBundleFormat(format string) error {
+	_, ok := bundleContentTypes[format]
+	if !ok {
+		return fmt.Errorf("Invalid bundle format: %s, expected: %s, %s", format, BundleFormatZip, BundleFormatTarGz)
+	}
+
+	return nil
+}
+
+// WriteBundle writes the archive of the format with the entries streaming their
+// content one after the other. The entries of the same name, ex. the same log
+// uploaded many times, get the number appended before the extension. The done
+// callback, if any, is called with the number of the entries written so far.
+func WriteBundle(w io.Writer, format string, entries []BundleEntry, done func(int64)) error {
+	err := ValidateBundleFormat(format)
+	if err != nil {
+		return err
+	}
+
+	var add func(name string, modTime time.Time, size int64, content io.Reader) error
+	var closers []io.Closer
+	switch format {
+	case BundleFormatZip:
+		zw := zip.NewWriter(w)
+		closers = append(closers, zw)
+		add = func(name string, modTime time.Time, size int64, content io.Reader) error {
+			fw, err := zw.CreateHeader(&zip.FileHeader{
+				Name:     name,
+				Method:   zip.Deflate,
+				Modified: modTime,
+			})
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(fw, content)
+			return err
+		}
+	case BundleFormatTarGz:
+		gw := gzip.NewWriter(w)
+		tw := tar.NewWriter(gw)
+		closers = append(closers, tw, gw)
+		add = func(name string, modTime time.Time, size int64, content io.Reader) error {
+			err := tw.WriteHeader(&tar.Header{
+				Name:     name,
+				Mode:     0644,
+				Size:     size,
+				ModTime:  modTime,
+				Typeflag: tar.TypeReg,
+			})
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, content)
+			return err
+		}
+	}
+
+	names := make(map[string]int)
+	for i, entry := range entries {
+		name := bundleEntryName(names, entry.Name)
+		content, size, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("Error opening %s: %s", entry.Name, err)
+		}
+		err = add(name, entry.ModTime, size, content)
+		content.Close()
+		if err != nil {
+			return fmt.Errorf("Error writing %s: %s", entry.Name, err)
+		}
+		if done != nil {
+			done(int64(i + 1))
+		}
+	}
+
+	for _, c := range closers {
+		err = c.Close()
+		if err != nil {
+			return fmt.Errorf("Error closing bundle: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// bundleEntryName is the name unique within the archive
+func bundleEntryName(names map[string]int, name string) string {
+	name = strings.TrimLeft(path.Clean("/"+name), "/")
+	names[name]++
+	if names[name] == 1 {
+		return name
+	}
+
+	ext := path.Ext(name)
+	unique := fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), names[name], ext)
+	names[unique]++
+
+	return unique
+}
+
+// RunBundle produces the archive of the bundle run as the job. The files are
+// streamed from the store into the new file of the bundle category uploaded in
+// parts, so neither the files nor the archive are held as a whole. The progress
+// is recorded per file, the bundle ends up (D)one linked to the archive, the id
+// of the archive file is returned. The failed run keeps the bundle (R)unning
+// with the error for the job to retry, it is (F)ailed by the last attempt or
+// once the job is stopped. The run stops before the next file once the context
+// is done.
+func RunBundle(ctx context.Context, id string, last bool, progress JobProgress) (string, error) {
+	r, err := db.NewRepository()
+	if err != nil {
+		return "", fmt.Errorf("Error creating db repository: %s", err)
+	}
+	defer r.Close()
+
+	bs, count, err := r.ReadBundleById(id)
+	if err != nil {
+		return "", fmt.Errorf("Error reading db repository: %s", err)
+	}
+	if count == 0 {
+		return "", fmt.Errorf("No bundle: %s", id)
+	}
+
+	err = r.SetBundleState(bs[0].ID, BundleStateRunning, "")
+	if err != nil {
+		return "", fmt.Errorf("Error updating db repository: %s", err)
+	}
+
+	fileId, err := runBundle(ctx, r, bs[0], progress)
+	if err != nil {
+		state := BundleStateRunning
+		if last || ctx.Err() != nil {
+			state = BundleStateFailed
+		}
+		Log.Error("Bundle " + id + " failed, state " + state + " - " + err.Error())
+		serr := r.SetBundleState(bs[0].ID, state, err.Error())
+		if serr != nil {
+			Log.Error("Error updating db repository: " + serr.Error())
+		}
+		return "", err
+	}
+
+	return fileId, nil
+}
+
+// runBundle selects the files of the bundle and streams them into the archive.
+// The archive file of the failed run is removed with its object if uploaded,
+// so the retries do not leave the files behind.
+func runBundle(ctx context.Context, r db.FileStoreRepositoryGORM, b db.Bundle, progress JobProgress) (fileId string, err error) {
+	fss, count, err := r.ReadFilesForBundle(b.TenantID, b.DeviceID, b.Status, b.From, b.To, Setup.BundleMaxFiles+1)
+	if err != nil {
+		return "", fmt.Errorf("Error reading db repository: %s", err)
+	}
+	if count == 0 {
+		return "", fmt.Errorf("No files match the bundle")
+	}
+	if count > int64(Setup.BundleMaxFiles) {
+		return "", fmt.Errorf("More than %d files match the bundle", Setup.BundleMaxFiles)
+	}
+
+	err = r.SetBundleProgress(b.ID, count, 0)
+	if err != nil {
+		return "", fmt.Errorf("Error updating db repository: %s", err)
+	}
+
+	entries := make([]BundleEntry, len(fss))
+	for i := range fss {
+		fs := fss[i]
+		entries[i] = BundleEntry{
+			Name:    fs.DeviceID + "/" + fs.Name,
+			ModTime: fs.CreatedAt,
+			Open: func() (io.ReadCloser, int64, error) {
+				if ctx.Err() != nil {
+					return nil, 0, ctx.Err()
+				}
+				fsrep, err := NewRepository(FileBackend(fs), fs.TenantID, fs.DeviceID, fs.Name, fs.ID)
+				if err != nil {
+					return nil, 0, err
+				}
+				return fsrep.FileObjectReader()
+			},
+		}
+	}
+
+	// The archive is the file of the tenant stored like the uploaded ones
+	name := "bundle-" + *b.UUID + "." + b.Format
+	contentType := bundleContentTypes[b.Format]
+	metadata := db.Metadata{"category": BundleCategory, "bundle": *b.UUID}
+	fss, _, err = r.Create(b.TenantID, b.DeviceID, name, "SHA256", "", 0, contentType, metadata)
+	if err != nil {
+		return "", fmt.Errorf("Error creating file: %s", err)
+	}
+	file := fss[0]
+	var fsrep Repository
+	var version string
+	uploaded := false
+	defer func() {
+		if err == nil {
+			return
+		}
+		if uploaded {
+			derr := fsrep.WithVersion(version).FileObjectDelete()
+			if derr != nil {
+				Log.Error("Error removing archive object of bundle " + *b.UUID + " - " + derr.Error())
+			}
+		}
+		derr := r.DeleteBundleFile(file.ID)
+		if derr != nil {
+			Log.Error("Error removing archive file of bundle " + *b.UUID + " - " + derr.Error())
+		}
+	}()
+
+	tss, _, err := r.ReadTenantStore(b.TenantID)
+	if err != nil {
+		return "", fmt.Errorf("Error reading tenant store: %s", err)
+	}
+	backend := TenantBackend(tss)
+	backend.UUID = *file.UUID
+	fsrep, err = NewRepository(backend, b.TenantID, b.DeviceID, name, file.ID)
+	if err != nil {
+		return "", fmt.Errorf("Error creating store repository: %s", err)
+	}
+	location, bucket := fsrep.BucketLocation()
+	err = fsrep.AssureBucketExist()
+	if err != nil {
+		return "", fmt.Errorf("Error provisioning bucket %s: %s", bucket, err)
+	}
+	err = r.SetBucketLocation(file.ID, bucket, location, fsrep.ObjectName(), backend.Credentials, fsrep.ObjectKeyLayout())
+	if err != nil {
+		return "", fmt.Errorf("Error updating db repository: %s", err)
+	}
+	encryption, encryptionKeyID := fsrep.Encryption()
+	err = r.SetEncryption(file.ID, encryption, encryptionKeyID)
+	if err != nil {
+		return "", fmt.Errorf("Error updating db repository: %s", err)
+	}
+
+	// The archive is written into the pipe read by the upload, the checksum
+	// and the size are taken on the way
+	h, _ := NewChecksumHash("SHA256")
+	var size byteCounter
+	pr, pw := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		err := WriteBundle(io.MultiWriter(pw, h, &size), b.Format, entries, func(done int64) {
+			perr := r.SetBundleProgress(b.ID, count, done)
+			if perr != nil {
+				Log.Error("Error updating progress of bundle " + *b.UUID + " - " + perr.Error())
+			}
+			if progress != nil {
+				progress(done, count)
+			}
+		})
+		pw.CloseWithError(err)
+		written <- err
+	}()
+
+	err = fsrep.FileObjectUpload(pr, contentType, metadata)
+	pr.CloseWithError(err)
+	werr := <-written
+	if werr != nil {
+		return "", werr
+	}
+	if err != nil {
+		return "", err
+	}
+	uploaded = true
+
+	// The archive is pinned to its version in the versioned bucket
+	version, err = fsrep.FileObjectVersion()
+	if err != nil {
+		return "", err
+	}
+	if version != "" {
+		err = r.SetVersionID(file.ID, version)
+		if err != nil {
+			return "", fmt.Errorf("Error updating db repository: %s", err)
+		}
+	}
+
+	// The archive was hashed while written, its checksum is the verified one
+	sum := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	_, _, err = r.UpdateById(*file.UUID, "C", "SHA256", sum, int64(size))
+	if err != nil {
+		return "", fmt.Errorf("Error updating db repository: %s", err)
+	}
+	err = r.UpdateStatusById(file.ID, "C", sum)
+	if err != nil {
+		return "", fmt.Errorf("Error updating db repository: %s", err)
+	}
+	err = r.CompleteBundle(b.ID, file.ID, int64(size))
+	if err != nil {
+		return "", fmt.Errorf("Error updating db repository: %s", err)
+	}
+	Log.Info("Bundle " + *b.UUID + fmt.Sprintf(" of %d files and %d bytes stored in file ", count, size) + *file.UUID)
+
+	return *file.UUID, nil
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"strings"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// checksumSizes are the checksum algorithms supported by the store
+// with the size of the digest in bytes
+var checksumSizes = map[string]int{
+	"SHA256": 32,
+	"SHA1":   20,
+	"CRC32C": 4,
+	"CRC32":  4,
+	"MD5":    16,
+}
+
+// checksumHeaders are the request headers carrying the checksum of the content
+// of given algorithm in the PUT request to the store
+var checksumHeaders = map[string]string{
+	"SHA256": "X-Amz-Checksum-Sha256",
+	"SHA1":   "X-Amz-Checksum-Sha1",
+	"CRC32C": "X-Amz-Checksum-Crc32c",
+	"CRC32":  "X-Amz-Checksum-Crc32",
+	"MD5":    "Content-Md5",
+}
+
+// ValidateChecksumType checks the checksum algorithm is supported by the store
+func ValidateChecksumType(cksumType string) error {
+	_, ok := checksumSizes[strings.ToUpper(cksumType)]
+	if !ok {
+		return fmt.Errorf("Invalid checksum type: %s, expected: SHA256, SHA1, CRC32C, CRC32, MD5", cksumType)
+	}
+
+	return nil
+}
+
+// ChecksumBase64 validates the checksum of a given algorithm and converts it
+// into the base64 form required by the x-amz-checksum-* and Content-MD5 headers.
+// The client may provide it as hex string (ex. sha256sum output) or already
+// base64 encoded, the result is the same for both so it may be compared.
+func ChecksumBase64(cksumType, cksum string) (string, error) {
+	err := ValidateChecksumType(cksumType)
+	if err != nil {
+		return "", err
+	}
+	size := checksumSizes[strings.ToUpper(cksumType)]
+
+	if len(cksum) == hex.EncodedLen(size) {
+		sum, err := hex.DecodeString(cksum)
+		if err == nil {
+			return base64.StdEncoding.EncodeToString(sum), nil
+		}
+	}
+
+	sum, err := base64.StdEncoding.DecodeString(cksum)
+	if err != nil || len(sum) != size {
+		return "", fmt.Errorf("Invalid %s checksum, expected hex or base64 encoding: %s", cksumType, cksum)
+	}
+
+	return base64.StdEncoding.EncodeToString(sum), nil
+}
+
+// NewChecksumHash is the hash of a given algorithm calculating the checksum
+// of the content the same way as the store does
+func NewChecksumHash(cksumType string) (hash.Hash, error) {
+	switch strings.ToUpper(cksumType) {
+	case "SHA256":
+		return sha256.New(), nil
+	case "SHA1":
+		return sha1.New(), nil
+	case "CRC32C":
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	case "CRC32":
+		return crc32.NewIEEE(), nil
+	case "MD5":
+		return md5.New(), nil
+	}
+
+	return nil, fmt.Errorf("Invalid checksum type: %s, expected: SHA256, SHA1, CRC32C, CRC32, MD5", cksumType)
+}
+
+// ChecksumHeader is the name of the request header carrying the checksum
+// of a given algorithm
+func ChecksumHeader(cksumType string) string {
+	return checksumHeaders[strings.ToUpper(cksumType)]
+}
+
+// VerifyFileObjectChecksum compares the checksum of the object kept by the store
+// with the one declared by the client on create. It returns the new status of
+// the file: (C)reated if they match or (X) for checksum mismatch. There is nothing
+// to verify when the client did not declare the checksum.
+func VerifyFileObjectChecksum(fs Repository, cksumType, cksum string) (string, error) {
+	if cksum == "" {
+		return "C", nil
+	}
+
+	expected, err := ChecksumBase64(cksumType, cksum)
+	if err != nil {
+		return "", err
+	}
+
+	stored, err := fs.FileObjectChecksum(cksumType)
+	if err != nil {
+		return "", err
+	}
+
+	if stored != expected {
+		Log.Error("Checksum mismatch of object " + fs.ObjectName() +
+			": expected " + cksumType + " " + expected + ", stored " + stored)
+		return "X", nil
+	}
+
+	return "C", nil
+}
+
+// VerifyFile is the status of the uploaded object of the file with the base64
+// checksum of its content verified by the store, the empty one if there was
+// none to compare. The object assembled from the parts of the multipart upload
+// has no checksum of the whole content, it stays (U)ploaded and not verified.
+func VerifyFile(fsrep Repository, fs db.FileStore) (string, string, error) {
+	if fs.PartCount > 0 {
+		return "U", "", nil
+	}
+
+	status, err := VerifyFileObjectChecksum(fsrep, fs.CheckSumType, fs.CheckSum)
+	if err != nil || status != "C" || fs.CheckSum == "" {
+		return status, "", err
+	}
+	verified, err := ChecksumBase64(fs.CheckSumType, fs.CheckSum)
+	if err != nil {
+		return "", "", err
+	}
+
+	return status, verified, nil
+}
+
+// etagChecksumMD5 converts the etag of the object into the base64 MD5 checksum.
+// It is valid only for the objects uploaded in one part without KMS encryption.
+func etagChecksumMD5(etag string) string {
+	sum, err := hex.DecodeString(strings.Trim(etag, "\""))
+	if err != nil {
+		return ""
+	}
+
+	return base64.StdEncoding.EncodeToString(sum)
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// The server side encryption modes, the empty one means no encryption
+const (
+	EncryptionSSES3  = "SSE-S3"
+	EncryptionSSEKMS = "SSE-KMS"
+	EncryptionSSEC   = "SSE-C"
+)
+
+const (
+	sseAlgorithmAES256 = "AES256"
+)
+
+var (
+	// The secrets of the key files once read
+	keyFileSecrets sync.Map
+)
+
+// ValidateEncryption checks the name of the encryption mode
+func ValidateEncryption(mode string) error {
+	switch mode {
+	case "", EncryptionSSES3, EncryptionSSEKMS, EncryptionSSEC:
+		return nil
+	}
+
+	return fmt.Errorf("Invalid encryption: %s, expecting: SSE-S3, SSE-KMS, SSE-C", mode)
+}
+
+// serverSideEncryption holds the parameters of the encryption of the object
+// to be put to the requests on it. The customer key is used with SSE-C only.
+type serverSideEncryption struct {
+	mode        string
+	kmsKeyID    string
+	customerKey string
+}
+
+// newServerSideEncryption resolves the encryption of the object. The new object
+// is encrypted with the mode of the backend or of the store kind, the existing
+// one with the mode recorded with it. The SSE-C key of the tenant is derived
+// from the secret in the key file of the store kind.
+func newServerSideEncryption(be Backend, tid string) (serverSideEncryption, error) {
+	err, mode, kmsKeyID, keyFile := LoadEncryptionRepositoryEnv(be.Kind)
+	if err != nil {
+		return serverSideEncryption{}, err
+	}
+
+	sse := serverSideEncryption{
+		mode:     mode,
+		kmsKeyID: kmsKeyID,
+	}
+	if be.Object != "" || be.Encryption != "" {
+		sse.mode, sse.kmsKeyID = be.Encryption, be.EncryptionKeyID
+	}
+
+	if sse.mode == EncryptionSSEC {
+		if keyFile == "" {
+			return serverSideEncryption{},
+				fmt.Errorf("Missing key file of encryption %s in store: %s", sse.mode, be.Kind)
+		}
+		secret, err := readKeyFileSecret(keyFile)
+		if err != nil {
+			return serverSideEncryption{}, err
+		}
+		sse.customerKey = string(TenantEncryptionKey(secret, tid))
+	}
+
+	return sse, nil
+}
+
+// TenantEncryptionKey derives the AES256 key of the tenant from the secret
+func TenantEncryptionKey(secret []byte, tid string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(tid))
+
+	return mac.Sum(nil)
+}
+
+// readKeyFileSecret gets the secret from the key file, it is read only once
+func readKeyFileSecret(keyFile string) ([]byte, error) {
+	if secret, ok := keyFileSecrets.Load(keyFile); ok {
+		return secret.([]byte), nil
+	}
+
+	secret, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading key file %s: %s", keyFile, err)
+	}
+	secret = bytes.TrimSpace(secret)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("Empty key file: %s", keyFile)
+	}
+	keyFileSecrets.Store(keyFile, secret)
+
+	return secret, nil
+}
+
+// serverSideEncryption is the value of the encryption header of the object creation
+func (sse serverSideEncryption) serverSideEncryption() (*string, *string) {
+	switch sse.mode {
+	case EncryptionSSES3:
+		return aws.String(s3.ServerSideEncryptionAes256), nil
+	case EncryptionSSEKMS:
+		if sse.kmsKeyID == "" {
+			return aws.String(s3.ServerSideEncryptionAwsKms), nil
+		}
+		return aws.String(s3.ServerSideEncryptionAwsKms), aws.String(sse.kmsKeyID)
+	}
+
+	return nil, nil
+}
+
+// customer is the algorithm and the key to be sent with each request on the SSE-C object
+func (sse serverSideEncryption) customer() (*string, *string) {
+	if sse.mode != EncryptionSSEC {
+		return nil, nil
+	}
+
+	return aws.String(sseAlgorithmAES256), aws.String(sse.customerKey)
+}
+
+// signedHeaders are the headers signed into the presigned url the client
+// must send with the request exactly as given, ex. the SSE-C key which must
+// come with every request reading or writing the content
+func signedHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for key, values := range header {
+		key = strings.ToLower(key)
+		if key == "host" {
+			continue
+		}
+		headers[key] = strings.Join(values, ",")
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+
+	return headers
+}
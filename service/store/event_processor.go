@@ -2,6 +2,7 @@ package store
 
 import (
 	"fmt"
+	"net/url"
 
 	"github.com/minio/minio-go/v7/pkg/notification"
 
@@ -15,8 +16,9 @@ import (
 // The mapping goes like this:
 //   s3:ObjectCreated:*  -> (C)reated
 // doing update on the initial record created in state = (N)ew
-// The access to the object may cause change to cksum or size
-// so the values must be put in sync.
+// The created object is verified against the checksum declared by the client
+// and in case of mismatch the status is set to (X). The object assembled from
+// parts is (U)ploaded with no verification.
 func EventProcessor(info notification.Info) error {
 	Log.Info("Processing events: " + fmt.Sprintf("%d", len(info.Records)))
 
@@ -27,23 +29,73 @@ func EventProcessor(info notification.Info) error {
 	}
 	defer r.Close()
 	
-	// For each event received within this info
+	// For each event received within this info, the failed one does not stop
+	// the others
+	failed := 0
 	for _, event := range info.Records {
-		Log.Info("Processing event " + fmt.Sprintf("%+v", info))
+		Log.Info("Processing event " + fmt.Sprintf("%+v", event))
 
-		// Extract key data from the event
-		fullObjectName := "aws:s3:" + event.S3.Bucket.Name + ":" + event.S3.Object.Key
-		status := mapEventNameToStatus(event.EventName)
+		err = processEvent(r, event)
+		if err != nil {
+			Log.Error("Error processing event of object " + event.S3.Bucket.Name + "/" + event.S3.Object.Key + " - " + err.Error())
+			failed++
+		}
+	}
 
-		// Get the db record in sync with file store
-		count, err := r.UpdateByFullObjectName(fullObjectName, status)
+	if failed > 0 {
+		return fmt.Errorf("Failed to process %d of %d events", failed, len(info.Records))
+	}
+
+	return nil
+}
+
+// processEvent brings the db record of the object of the event in sync
+// with the file store
+func processEvent(r db.FileStoreRepositoryGORM, event notification.Event) error {
+	// Extract key data from the event, the object key is url encoded
+	object, err := url.QueryUnescape(event.S3.Object.Key)
+	if err != nil {
+		return fmt.Errorf("Error decoding object key: %s", err)
+	}
+	status := mapEventNameToStatus(event.EventName)
+
+	// Find the db record of the object
+	fss, count, err := r.ReadByBucketObject(event.S3.Bucket.Name, object)
+	if err != nil {
+		return fmt.Errorf("Error reading db repository: %s", err)
+	} else if count != 1 {
+		Log.Error("Invalid number of records found for object: " + object)
+		return nil
+	}
+
+	// The content must match the checksum declared on create
+	var verified string
+	if status == "C" {
+		fsrep, err := NewRepository(FileBackend(fss[0]), fss[0].TenantID, fss[0].DeviceID, fss[0].Name, fss[0].ID)
+		if err != nil {
+			return fmt.Errorf("Error creating store repository: %s", err)
+		}
+		status, verified, err = VerifyFile(fsrep, fss[0])
+		if err != nil {
+			return fmt.Errorf("Error verifying object checksum: %s", err)
+		}
+	}
+
+	// Get the db record in sync with file store
+	err = r.UpdateStatusById(fss[0].ID, status, verified)
+	if err != nil {
+		return fmt.Errorf("Error updating db repository: %s", err)
+	}
+
+	// The revision is pinned to the version of the object in the versioned bucket
+	version := event.S3.Object.VersionID
+	if (status == "C" || status == "U") && version != "" && version != objectVersionNull {
+		err = r.SetVersionID(fss[0].ID, version)
 		if err != nil {
 			return fmt.Errorf("Error updating db repository: %s", err)
-		} else if count != 1 {
-			Log.Error("Invalid number of records updated")
 		}
 	}
-	
+
 	return nil
 }
 
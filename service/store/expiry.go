@@ -0,0 +1,55 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// TenantPresignLimits are the configured limits of the presigned urls with
+// the ones of the methods given by the tenant store mapping overriding them
+func TenantPresignLimits(tss []db.TenantStore) (PresignLimits, error) {
+	limits := make(PresignLimits)
+	for method, limit := range Setup.PresignLimits {
+		limits[method] = limit
+	}
+	if len(tss) == 0 || tss[0].PresignLimits == "" {
+		return limits, nil
+	}
+
+	tenantLimits, err := ParsePresignLimits(tss[0].PresignLimits)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid presign limits of tenant %s: %s", tss[0].TenantID, err)
+	}
+	for method, limit := range tenantLimits {
+		limits[method] = limit
+	}
+
+	return limits, nil
+}
+
+// PresignExpiry is the validity of the url of the method requested by the client
+// clamped to the limits of the method. The validity of the store is used when
+// none is requested.
+func PresignExpiry(method string, expiresIn, fallback time.Duration, limits PresignLimits) time.Duration {
+	expiry := expiresIn
+	if expiry <= 0 {
+		expiry = fallback
+	}
+
+	if limit, ok := limits[method]; ok {
+		if expiry < limit.Min {
+			expiry = limit.Min
+		}
+		if expiry > limit.Max {
+			expiry = limit.Max
+		}
+	}
+	if expiry > MaxPresignDuration {
+		expiry = MaxPresignDuration
+	}
+
+	return expiry
+}
@@ -0,0 +1,304 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// The types of the jobs
+const (
+	JobTypeBundle    = "bundle"
+	JobTypeRekey     = "rekey"
+	JobTypeRetention = "retention"
+)
+
+// The states of the job
+const (
+	JobStatePending   = "P"
+	JobStateRunning   = "R"
+	JobStateDone      = "D"
+	JobStateFailed    = "F"
+	JobStateCancelled = "C"
+)
+
+const (
+	// maxJobBackoff caps the delay of the retry growing with the attempts
+	maxJobBackoff = time.Hour
+)
+
+// JobProgress reports the number of the items of the job done so far out of
+// the total, the total is 0 when not known up front
+type JobProgress func(done, total int64)
+
+// JobHandler validates the payload of the job type on submit and runs the job.
+// The run is stopped by the context when the job is cancelled or its lease is
+// lost, the result is stored with the done job as JSON.
+type JobHandler struct {
+	Validate func(payload db.JobPayload) error
+	Run      func(ctx context.Context, job db.Job, progress JobProgress) (interface{}, error)
+}
+
+var (
+	// jobHandlers are the handlers of the job types run by the workers
+	jobHandlers = map[string]JobHandler{
+		JobTypeBundle:    {validateBundleJob, runBundleJob},
+		JobTypeRekey:     {validateRekeyJob, runRekeyJob},
+		JobTypeRetention: {validateRetentionJob, runRetentionJob},
+	}
+)
+
+// bundleJobPayload is the bundle to be produced by the job
+type bundleJobPayload struct {
+	BundleID string `json:"bundle_id"`
+}
+
+// rekeyJobPayload are the rekey parameters, the configured layout by default
+type rekeyJobPayload struct {
+	Layout int  `json:"layout,omitempty"`
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// JobTypes are the types of the jobs which may be submitted
+func JobTypes() []string {
+	types := make([]string, 0, len(jobHandlers))
+	for t := range jobHandlers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	return types
+}
+
+// ValidateJob checks the type of the job is known and its payload is valid
+func ValidateJob(jobType string, payload db.JobPayload) error {
+	h, ok := jobHandlers[jobType]
+	if !ok {
+		return fmt.Errorf("Invalid job type: %s, expected one of: %v", jobType, JobTypes())
+	}
+
+	return h.Validate(payload)
+}
+
+// JobBackoff is the delay of the retry after the failed attempt, doubled
+// with each attempt up to the limit
+func JobBackoff(attempts int, base time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempts && d < maxJobBackoff; i++ {
+		d *= 2
+	}
+	if d > maxJobBackoff {
+		d = maxJobBackoff
+	}
+
+	return d
+}
+
+// StartJobWorkers starts the workers taking the jobs from the db queue,
+// each with its own lease owner name unique across the replicas
+func StartJobWorkers(n int) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "fs"
+	}
+	for i := 0; i < n; i++ {
+		owner := fmt.Sprintf("%s-%d-%d", host, os.Getpid(), i)
+		go runJobWorker(owner)
+	}
+	Log.Info("Started job workers: " + fmt.Sprintf("%d", n))
+}
+
+// runJobWorker takes the jobs due one after the other waiting the poll
+// interval when there are none
+func runJobWorker(owner string) {
+	var r db.FileStoreRepositoryGORM
+	var err error
+	for {
+		r, err = db.NewRepository()
+		if err == nil {
+			break
+		}
+		Log.Error("Job worker " + owner + " error creating db repository - " + err.Error())
+		time.Sleep(Setup.JobPoll)
+	}
+	defer r.Close()
+
+	for {
+		js, count, err := r.LeaseJob(JobTypes(), owner, Setup.JobLease)
+		if err != nil {
+			Log.Error("Job worker " + owner + " error leasing job - " + err.Error())
+		}
+		if count == 0 {
+			time.Sleep(Setup.JobPoll)
+			continue
+		}
+		runJob(r, js[0], owner)
+	}
+}
+
+// runJob runs the leased job renewing its lease until it ends, the failed job
+// is retried after the backoff until its attempts are exhausted
+func runJob(r db.FileStoreRepositoryGORM, job db.Job, owner string) {
+	id := *job.UUID
+	Log.Info("Job " + id + " of type " + job.Type + fmt.Sprintf(" attempt %d/%d", job.Attempts, job.MaxAttempts) + " run by " + owner)
+
+	// The job taken over from the worker which is gone may have used its last attempt
+	if job.Attempts > job.MaxAttempts {
+		_, err := r.FailJob(job.ID, owner, "Lease expired on the last attempt: "+job.Error)
+		if err != nil {
+			Log.Error("Job " + id + " error updating db repository - " + err.Error())
+		}
+		return
+	}
+
+	var done, total int64
+	progress := func(d, t int64) {
+		atomic.StoreInt64(&done, d)
+		atomic.StoreInt64(&total, t)
+	}
+
+	// The lease is renewed with the progress, the job is stopped once it is lost
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(Setup.JobLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				count, err := r.RenewJobLease(job.ID, owner, Setup.JobLease, atomic.LoadInt64(&done), atomic.LoadInt64(&total))
+				if err != nil {
+					Log.Error("Job " + id + " error renewing lease - " + err.Error())
+					continue
+				}
+				if count == 0 {
+					Log.Info("Job " + id + " cancelled or lease lost, stopping")
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	res, err := runJobHandler(ctx, job, progress)
+	cancel()
+	if err != nil {
+		if job.Attempts < job.MaxAttempts {
+			backoff := JobBackoff(job.Attempts, Setup.JobBackoff)
+			Log.Error("Job " + id + " failed, retry in " + backoff.String() + " - " + err.Error())
+			_, err = r.RetryJob(job.ID, owner, err.Error(), time.Now().Add(backoff))
+		} else {
+			Log.Error("Job " + id + " failed - " + err.Error())
+			_, err = r.FailJob(job.ID, owner, err.Error())
+		}
+		if err != nil {
+			Log.Error("Job " + id + " error updating db repository - " + err.Error())
+		}
+		return
+	}
+
+	var result db.JobPayload
+	if res != nil {
+		result, err = json.Marshal(res)
+		if err != nil {
+			Log.Error("Job " + id + " error encoding result - " + err.Error())
+		}
+	}
+	count, err := r.CompleteJob(job.ID, owner, atomic.LoadInt64(&done), atomic.LoadInt64(&total), result)
+	if err != nil {
+		Log.Error("Job " + id + " error updating db repository - " + err.Error())
+		return
+	}
+	if count == 0 {
+		Log.Info("Job " + id + " finished after it was cancelled or its lease lost")
+		return
+	}
+	Log.Info("Job " + id + " done")
+}
+
+// runJobHandler runs the job by the handler of its type
+func runJobHandler(ctx context.Context, job db.Job, progress JobProgress) (interface{}, error) {
+	h, ok := jobHandlers[job.Type]
+	if !ok {
+		return nil, fmt.Errorf("Invalid job type: %s", job.Type)
+	}
+
+	return h.Run(ctx, job, progress)
+}
+
+// validateBundleJob checks the bundle is given
+func validateBundleJob(payload db.JobPayload) error {
+	var p bundleJobPayload
+	err := json.Unmarshal(payload, &p)
+	if err != nil {
+		return fmt.Errorf("Invalid bundle job payload: %s", err)
+	}
+	if !db.IsUUID(p.BundleID) {
+		return fmt.Errorf("Invalid bundle job payload, bundle_id: %s", p.BundleID)
+	}
+
+	return nil
+}
+
+// runBundleJob produces the archive of the bundle
+func runBundleJob(ctx context.Context, job db.Job, progress JobProgress) (interface{}, error) {
+	var p bundleJobPayload
+	err := json.Unmarshal(job.Payload, &p)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid bundle job payload: %s", err)
+	}
+
+	fileId, err := RunBundle(ctx, p.BundleID, job.Attempts >= job.MaxAttempts, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{"bundle_id": p.BundleID, "file_id": fileId}, nil
+}
+
+// validateRekeyJob checks the layout if given
+func validateRekeyJob(payload db.JobPayload) error {
+	var p rekeyJobPayload
+	if len(payload) == 0 {
+		return nil
+	}
+	err := json.Unmarshal(payload, &p)
+	if err != nil {
+		return fmt.Errorf("Invalid rekey job payload: %s", err)
+	}
+	if p.Layout != 0 && (p.Layout < ObjectKeyLayoutLegacy || p.Layout > ObjectKeyLayoutHashedPrefix) {
+		return fmt.Errorf("Invalid rekey job payload, layout: %d", p.Layout)
+	}
+
+	return nil
+}
+
+// runRekeyJob moves the objects to the keys of the layout
+func runRekeyJob(ctx context.Context, job db.Job, progress JobProgress) (interface{}, error) {
+	p := rekeyJobPayload{Layout: Setup.ObjectKeyLayout}
+	if len(job.Payload) != 0 {
+		err := json.Unmarshal(job.Payload, &p)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid rekey job payload: %s", err)
+		}
+		if p.Layout == 0 {
+			p.Layout = Setup.ObjectKeyLayout
+		}
+	}
+
+	moved, err := RekeyFileObjects(ctx, p.Layout, p.DryRun, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]int64{"moved": moved}, nil
+}
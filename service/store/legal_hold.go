@@ -0,0 +1,14 @@
+package store
+
+import (
+	"errors"
+)
+
+var (
+	// ErrFileLegalHold refuses the removal of the file under legal hold
+	ErrFileLegalHold = errors.New("File is under legal hold")
+
+	// ErrObjectLockUnsupported is the legal hold of the object in the bucket
+	// created without the object lock, the hold is kept by the db only
+	ErrObjectLockUnsupported = errors.New("Object lock is not enabled for the bucket")
+)
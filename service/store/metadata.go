@@ -0,0 +1,70 @@
+package store
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// The limits of S3 object tags the metadata is propagated as
+const (
+	MetadataMaxKeys        = 10
+	MetadataMaxKeyLength   = 128
+	MetadataMaxValueLength = 256
+)
+
+var (
+	// The key must be a valid name of the x-amz-meta- header
+	metadataKeyPattern = regexp.MustCompile("^[a-z0-9][a-z0-9_.-]*$")
+	// The value must be printable US-ASCII as it goes in the header
+	metadataValuePattern = regexp.MustCompile("^[\x20-\x7e]*$")
+)
+
+// ValidateMetadata checks the metadata of the file may be stored with
+// the object as its user metadata and tags
+func ValidateMetadata(metadata map[string]string) error {
+	if len(metadata) > MetadataMaxKeys {
+		return fmt.Errorf("Too many metadata keys: %d, expected at most: %d", len(metadata), MetadataMaxKeys)
+	}
+
+	for key, value := range metadata {
+		if len(key) > MetadataMaxKeyLength || !metadataKeyPattern.MatchString(key) {
+			return fmt.Errorf("Invalid metadata key: %s", key)
+		}
+		if len(value) > MetadataMaxValueLength || !metadataValuePattern.MatchString(value) {
+			return fmt.Errorf("Invalid metadata value of key: %s", key)
+		}
+	}
+
+	return nil
+}
+
+// objectMetadata is the user metadata of the object, sent as x-amz-meta- headers
+func objectMetadata(metadata map[string]string) map[string]*string {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	m := make(map[string]*string, len(metadata))
+	for key, value := range metadata {
+		m[key] = aws.String(value)
+	}
+
+	return m
+}
+
+// objectTagging is the url encoded tag set of the object, sent as x-amz-tagging header
+func objectTagging(metadata map[string]string) *string {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	tags := make(url.Values, len(metadata))
+	for key, value := range metadata {
+		tags.Set(key, value)
+	}
+
+	return aws.String(tags.Encode())
+}
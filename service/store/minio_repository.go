@@ -3,15 +3,17 @@ package store
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
-	"net/url"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 
 	. "fs/service/config"
+	"fs/service/db"
 )
 
 // Stores necessary data to perform object operations
@@ -19,7 +21,7 @@ type MinioRepository struct {
 	bucket   string
 	object   string
 	client   *minio.Client
-	presign  int
+	presign  time.Duration
 	region   string
 	filename string
 }
@@ -31,22 +33,39 @@ func (r MinioRepository) FullObjectName() string {
 	return Setup.UseFileStore + ":" + r.bucket + ":" + r.object
 }
 
+// ObjectName is the key of the object in the bucket
+func (r MinioRepository) ObjectName() string {
+	return r.object
+}
+
+// PresignDuration is the validity of the presigned urls
+func (r MinioRepository) PresignDuration() time.Duration {
+	return r.presign
+}
+
+// WithPresignDuration is the copy of the repository signing the urls valid
+// for the given duration
+func (r MinioRepository) WithPresignDuration(d time.Duration) MinioRepository {
+	r.presign = d
+	return r
+}
+
 // NewMinioRepository creates a object with client connection to the datastore.
 // It uses the config for AWS key env variables to make the connection.
-func NewMinioRepository(tid, did, name string) (MinioRepository, error) {
+func NewMinioRepository(tid, did, name string, id uint) (MinioRepository, error) {
 	// Load, validate, convert the configuration loaded from config
 	err, endpoint, port, bucket, accessKeyID, secretAccessKey, region, presign, secure := loadRepositoryEnv()
 	if err != nil {
 		return MinioRepository{},
 			fmt.Errorf("Error collecting env values: %s", err)
 	}
-	object := objectKeyName(tid, did, name)
+	object := objectKeyName(id, tid, did, name)
 	logRepositoryEnv(endpoint, port, bucket, object, accessKeyID, secretAccessKey, region, presign, secure)
 	// Do the conversion in 2nd step as some of the value may be malformed
 	presignVal, err := strconv.Atoi(presign)
 	if err != nil {
 		return MinioRepository{},
-			fmt.Errorf("Invalid presign (exp. integer) value: %s - %s", secure, err)
+			fmt.Errorf("Invalid presign (exp. integer) value: %s - %s", presign, err)
 	}
 	secureVal, err := strconv.ParseBool(secure)
 	if err != nil {
@@ -82,109 +101,179 @@ func NewMinioRepository(tid, did, name string) (MinioRepository, error) {
 		bucket:   bucket,
 		object:   object,
 		client:   client,
-		presign:  presignVal,
+		presign:  time.Duration(presignVal) * time.Minute,
 		region:   region,
 		filename: name,
 	}, nil
 }
 
 // FileObjectPresignedPostURL produces presigned URL to be accessed by the clients
-// with a seq of name/content form data to be used in curl (for example) call
+// with a seq of name/content form fields to be used in curl (for example) call
 // with -F or --form option for each of the mappings. The object is not created
 // as it will be done with resigned POST operation.
-func (r MinioRepository) FileObjectPresignedURL(cksum string, size int64) (string, map[string]string, error) {
+func (r MinioRepository) FileObjectPresignedURL(cksum string, size int64) (db.FileAccess, error) {
 	// The bucket must exist, if not, then create it
 	err := r.AssureBucketExist()
 	if err != nil {
-		return "", nil, fmt.Errorf("Error checking bucket; %s", err)
+		return db.FileAccess{}, fmt.Errorf("Error checking bucket; %s", err)
 	}
 
 	// Policy with upload restrictions
 	policy := minio.NewPostPolicy()
 	policy.SetBucket(r.bucket)
 	policy.SetKey(r.object)
-	policy.SetExpires(time.Now().UTC().AddDate(0, 0, r.presign)) // expires in N day(s)
+	policy.SetExpires(time.Now().UTC().Add(r.presign))
 	policy.SetContentLengthRange(0, size)
 
 	// Get the POST ready URL with form key/value object
 	url, formData, err := r.client.PresignedPostPolicy(context.Background(), policy)
 	if err != nil {
-		return "", nil, fmt.Errorf("Error getting presigned url: %s", err)
+		return db.FileAccess{}, fmt.Errorf("Error getting presigned url: %s", err)
 	}
 	Log.Debug("Got presigned URL: " + url.String() + " - " + fmt.Sprintf("%+v", formData))
 
-	return url.String(), formData, nil
+	return presignedAccess(http.MethodPost, url.String(), nil, formData, r.presign), nil
 }
 
 // FileObjectPresignedGetURL provides url for GET mode acceess to the existing object.
-// It mey be used to implement Read operation on the object. The precondition is
-// that the object exists.
-func (r MinioRepository) FileObjectPresignedGetURL(cksum string, size int64) (string, error) {
+// The response headers may be overriden by the validated ones. It mey be used
+// to implement Read operation on the object. The precondition is that the object
+// exists.
+func (r MinioRepository) FileObjectPresignedGetURL(cksum string, size int64, response ResponseOverrides) (db.FileAccess, error) {
 	// The bucket and object must exist, if not, then error as POST had to be done
 	err := r.AssureBucketObjectExist()
 	if err != nil {
-		return "", fmt.Errorf("Error checking bucket object: %s", err)
+		return db.FileAccess{}, fmt.Errorf("Error checking bucket object: %s", err)
 	}
 
 	// Additional response header overrides supports:
-	// response-expires, response-content-type, response-cache-control, response-content-disposition
-	params := make(url.Values)
-	disposition := fmt.Sprintf("attachment; filename=\"%s\"", r.filename)
-	params.Set("response-content-disposition", disposition)
+	// response-content-type, response-cache-control, response-content-disposition
+	params := responseParams(response, r.filename)
 
 	// Produce presigned URL
 	url, err := r.client.PresignedGetObject(context.Background(),
 		r.bucket,
 		r.object,
-		time.Second * 24 * 60 * 60 * time.Duration(r.presign),
+		r.presign,
 		params)
+	if err != nil {
+		return db.FileAccess{}, fmt.Errorf("Error getting presigned url: %s", err)
+	}
 
-	return url.String(), nil
+	return presignedAccess(http.MethodGet, url.String(), nil, nil, r.presign), nil
 }
 
 // FileObjectPresignedHeadURL provides url for HEAD mode acceess to the existing object.
 // It mey be used to implement Read operation on the object. The precondition is
 // that the object exists.
-func (r MinioRepository) FileObjectPresignedHeadURL(cksum string, size int64) (string, error) {
+func (r MinioRepository) FileObjectPresignedHeadURL(cksum string, size int64) (db.FileAccess, error) {
 	// The bucket and object must exist, if not, then error as POST had to be done
 	err := r.AssureBucketObjectExist()
 	if err != nil {
-		return "", fmt.Errorf("Error checking bucket object: %s", err)
+		return db.FileAccess{}, fmt.Errorf("Error checking bucket object: %s", err)
 	}
 
-	// Additional response header overrides supports:
-	// response-expires, response-content-type, response-cache-control, response-content-disposition
-	params := make(url.Values)
-	disposition := fmt.Sprintf("attachment; filename=\"%s\"", r.filename)
-	params.Set("response-content-disposition", disposition)
-
 	// Produce presigned URL
 	url, err := r.client.PresignedHeadObject(context.Background(),
 		r.bucket,
 		r.object,
-		time.Second * 24 * 60 * 60 * time.Duration(r.presign),
-		params)
+		r.presign,
+		nil)
+	if err != nil {
+		return db.FileAccess{}, fmt.Errorf("Error getting presigned url: %s", err)
+	}
 
-	return url.String(), nil
+	return presignedAccess(http.MethodHead, url.String(), nil, nil, r.presign), nil
 }
 
 // FileObjectPresignedPutURL provides url for GET mode acceess to the existing object.
 // It may be used to implement Update operation on the object. The precondition is
-// that the object exists.
-func (r MinioRepository) FileObjectPresignedPutURL(cksum string, size int64) (string,  error) {
+// that the object exists. The checksum is signed into the url so the client must
+// send it in the x-amz-checksum-* or Content-MD5 header and the store verifies
+// the content. The content type declared by the client is signed in as well.
+func (r MinioRepository) FileObjectPresignedPutURL(cksumType, cksum string, size int64, contentType string) (db.FileAccess, error) {
 	// The bucket object must exist, if not, then error as POST had to be done
 	err := r.AssureBucketObjectExist()
 	if err != nil {
-		return "", fmt.Errorf("Error checking bucket object: %s", err)
+		return db.FileAccess{}, fmt.Errorf("Error checking bucket object: %s", err)
+	}
+
+	headers := make(http.Header)
+	if cksum != "" {
+		sum, err := ChecksumBase64(cksumType, cksum)
+		if err != nil {
+			return db.FileAccess{}, err
+		}
+		headers.Set(ChecksumHeader(cksumType), sum)
+	}
+	if contentType != "" {
+		headers.Set("Content-Type", contentType)
 	}
 
 	// Produce presigned URL
-	url, err := r.client.PresignedPutObject(context.Background(),
+	url, err := r.client.PresignHeader(context.Background(),
+		http.MethodPut,
 		r.bucket,
 		r.object,
-		time.Second * 24 * 60 * 60 * time.Duration(r.presign))
+		r.presign,
+		nil,
+		headers)
+	if err != nil {
+		return db.FileAccess{}, fmt.Errorf("Error getting presigned url: %s", err)
+	}
+
+	return presignedAccess(http.MethodPut, url.String(), signedHeaders(headers), nil, r.presign), nil
+}
+
+// FileObjectChecksum provides the checksum of given algorithm of the existing
+// object as calculated by the store on upload, base64 encoded.
+func (r MinioRepository) FileObjectChecksum(cksumType string) (string, error) {
+	info, err := r.client.StatObject(context.Background(),
+		r.bucket,
+		r.object,
+		minio.StatObjectOptions{Checksum: true})
+	if err != nil {
+		return "", fmt.Errorf("Error checking bucket object: %s", err)
+	}
 
-	return url.String(), nil
+	var sum string
+	switch strings.ToUpper(cksumType) {
+	case "SHA256":
+		sum = info.ChecksumSHA256
+	case "SHA1":
+		sum = info.ChecksumSHA1
+	case "CRC32C":
+		sum = info.ChecksumCRC32C
+	case "CRC32":
+		sum = info.ChecksumCRC32
+	case "MD5":
+		sum = etagChecksumMD5(info.ETag)
+	default:
+		return "", fmt.Errorf("Invalid checksum type: %s", cksumType)
+	}
+	Log.Debug("Got object checksum: " + cksumType + " " + sum)
+
+	return sum, nil
+}
+
+// FileObjectLegalHold sets or clears the object lock legal hold of the object,
+// the bucket must have been created with the object locking
+func (r MinioRepository) FileObjectLegalHold(on bool) error {
+	status := minio.LegalHoldDisabled
+	if on {
+		status = minio.LegalHoldEnabled
+	}
+
+	err := r.client.PutObjectLegalHold(context.Background(),
+		r.bucket,
+		r.object,
+		minio.PutObjectLegalHoldOptions{Status: &status})
+	if err != nil {
+		return fmt.Errorf("Failed to set legal hold: %s", err)
+	}
+	Log.Debug("Set legal hold " + status.String() + " of object: " + r.object)
+
+	return nil
 }
 
 // AssureBucketExist checks bucket existence and creates bucket if it does not exist
@@ -202,7 +291,7 @@ func (r MinioRepository) AssureBucketExist() error {
 			r.bucket,
 			minio.MakeBucketOptions{
 				Region:        r.region,
-				ObjectLocking: false,
+				ObjectLocking: Setup.ObjectLock,
 			})
 		if err != nil {
 			return err
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"fs/service/db"
+)
+
+const (
+	MultipartMinPartSize int64 = 5 << 20 // S3 limit of all but the last part
+	MultipartMaxPartSize int64 = 5 << 30 // S3 limit of a single part
+	MultipartMaxParts    int64 = 10000   // S3 limit of the number of parts
+)
+
+// MultipartPartSizes splits the object of a given size into parts of at most
+// part size bytes. The last part holds the remainder. The part size is kept
+// within the limits of the store. When the object would need more parts than
+// allowed the part size is increased accordingly.
+func MultipartPartSizes(size, partSize int64) ([]int64, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("Invalid object size: %d", size)
+	}
+	if partSize < MultipartMinPartSize {
+		partSize = MultipartMinPartSize
+	}
+	if partSize > MultipartMaxPartSize {
+		partSize = MultipartMaxPartSize
+	}
+	if (size+partSize-1)/partSize > MultipartMaxParts {
+		partSize = (size + MultipartMaxParts - 1) / MultipartMaxParts
+	}
+	if partSize > MultipartMaxPartSize {
+		return nil, fmt.Errorf("Object size too big for multipart upload: %d", size)
+	}
+
+	count := (size + partSize - 1) / partSize
+	sizes := make([]int64, count)
+	for i := int64(0); i < count-1; i++ {
+		sizes[i] = partSize
+	}
+	sizes[count-1] = size - partSize*(count-1)
+
+	return sizes, nil
+}
+
+// ValidateMultipartParts checks the parts reported to complete the upload were
+// all received by the store with the etags given and the sizes the object was
+// split into, so the object completed is the one declared on create
+func ValidateMultipartParts(sizes []int64, parts, received []db.FilePart) error {
+	if len(parts) != len(sizes) {
+		return fmt.Errorf("Invalid number of parts: %d, expected: %d", len(parts), len(sizes))
+	}
+
+	stored := make(map[int64]db.FilePart)
+	for _, part := range received {
+		stored[part.Number] = part
+	}
+	for i, part := range parts {
+		number := int64(i + 1)
+		if part.Number != number {
+			return fmt.Errorf("Invalid part: %d, expected: %d", part.Number, number)
+		}
+		sp, ok := stored[number]
+		if !ok {
+			return fmt.Errorf("Part %d not received by the store", number)
+		}
+		if strings.Trim(sp.ETag, `"`) != strings.Trim(part.ETag, `"`) {
+			return fmt.Errorf("Invalid etag of part %d: %s, received: %s", number, part.ETag, sp.ETag)
+		}
+		if sp.Size != sizes[i] {
+			return fmt.Errorf("Invalid size of part %d: %d, expected: %d", number, sp.Size, sizes[i])
+		}
+	}
+
+	return nil
+}
@@ -1,13 +1,28 @@
 package store
 
 import (
+	"crypto/sha256"
 	"fmt"
 
 	. "fs/service/config"
+	"fs/service/db"
 )
 
 const (
 	objectKeyNameSeparator string = "-"
+	objectKeyPathSeparator string = "/"
+)
+
+// The layouts of the object keys. The version is recorded with the file
+// so the key of the existing object is known when the default changes.
+// The rows recorded before the layouts were introduced have version 0, their
+// key is shared by the files of the same name with the same module of the id.
+// The legacy layout adds the id so that each revision has the object of its own.
+const (
+	ObjectKeyLayoutUntracked    = 0 // {id%module}-{tenant}-{device}-{name}
+	ObjectKeyLayoutLegacy       = 1 // {id%module}-{tenant}-{device}-{id}-{name}
+	ObjectKeyLayoutTenantDevice = 2 // {tenant}/{device}/{uuid}
+	ObjectKeyLayoutHashedPrefix = 3 // {hh}/{hh}/{uuid}
 )
 
 // objectKeyName provides uniform way of naming s3 bucket objects.
@@ -21,3 +36,32 @@ func objectKeyName(id uint, tid, did, name string) string {
 		did, objectKeyNameSeparator,
 		name)
 }
+
+// ObjectKey names the object of the file according to the layout. Only the
+// legacy layouts expose the identifiers and the name of the file, the others
+// use the public UUID of the file so the key is derived from the record and
+// the hashed prefix spreads the keys over the bucket partitions evenly.
+func ObjectKey(layout int, id uint, uuid, tid, did, name string) (string, error) {
+	switch layout {
+	case ObjectKeyLayoutUntracked:
+		return objectKeyName(id, tid, did, name), nil
+	case ObjectKeyLayoutLegacy:
+		return objectKeyName(id, tid, did, fmt.Sprintf("%d%s%s", id, objectKeyNameSeparator, name)), nil
+	case ObjectKeyLayoutTenantDevice:
+		if !db.IsUUID(uuid) {
+			return "", fmt.Errorf("Invalid public id of file %d: %s", id, uuid)
+		}
+		return tid + objectKeyPathSeparator + did + objectKeyPathSeparator + uuid, nil
+	case ObjectKeyLayoutHashedPrefix:
+		if !db.IsUUID(uuid) {
+			return "", fmt.Errorf("Invalid public id of file %d: %s", id, uuid)
+		}
+		sum := sha256.Sum256([]byte(uuid))
+		return fmt.Sprintf("%02x%s%02x%s%s",
+			sum[0], objectKeyPathSeparator,
+			sum[1], objectKeyPathSeparator,
+			uuid), nil
+	}
+
+	return "", fmt.Errorf("Invalid object key layout: %d", layout)
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+var (
+	// The headers of the client GET passed to the store, the range and
+	// the conditions on the object
+	proxyRequestHeaders = []string{
+		"Range",
+		"If-Range",
+		"If-Match",
+		"If-None-Match",
+		"If-Modified-Since",
+		"If-Unmodified-Since",
+	}
+
+	// The headers of the store GET response passed back to the client
+	proxyResponseHeaders = []string{
+		"Accept-Ranges",
+		"Cache-Control",
+		"Content-Disposition",
+		"Content-Length",
+		"Content-Range",
+		"Content-Type",
+		"ETag",
+		"Last-Modified",
+	}
+)
+
+// proxyClient sends the requests to the store on behalf of the client,
+// the streams of the large files may take long so the timeout is the one
+// of the proxy mode
+func proxyClient() *http.Client {
+	return &http.Client{
+		Timeout: Setup.ProxyTimeout,
+	}
+}
+
+// byteCounter counts the bytes of the stream written to it
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// ProxyUpload streams the content of the given size to the store by the presigned
+// PUT of the access not buffering it. The checksum of the given algorithm is
+// calculated on the fly and returned base64 encoded to be compared with the one
+// declared by the client, which is sent to the store as well if given. The checksum
+// is returned even if the store refuses the content as long as all of it was read,
+// ex. when the store finds it does not match the declared one.
+func ProxyUpload(access db.FileAccess, body io.Reader, size int64, cksumType, cksum string) (string, error) {
+	h, err := NewChecksumHash(cksumType)
+	if err != nil {
+		return "", err
+	}
+	var read byteCounter
+
+	req, err := http.NewRequest(access.Method, access.URL, io.TeeReader(body, io.MultiWriter(h, &read)))
+	if err != nil {
+		return "", fmt.Errorf("Error creating store request: %s", err)
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	for name, value := range access.Headers {
+		if name != "content-length" {
+			req.Header.Set(name, value)
+		}
+	}
+	if cksum != "" {
+		sum, err := ChecksumBase64(cksumType, cksum)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set(ChecksumHeader(cksumType), sum)
+	}
+
+	resp, err := proxyClient().Do(req)
+	var sum string
+	if int64(read) == size {
+		sum = base64.StdEncoding.EncodeToString(h.Sum(nil))
+	}
+	if err != nil {
+		return sum, fmt.Errorf("Error sending content to store: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return sum, fmt.Errorf("Store refused content: %s - %s", resp.Status, proxyErrorBody(resp))
+	}
+
+	return sum, nil
+}
+
+// ProxyDownload opens the presigned GET of the access passing the range
+// and the conditions of the client request. The response is to be streamed
+// to the client and closed by the caller.
+func ProxyDownload(access db.FileAccess, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(access.Method, access.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating store request: %s", err)
+	}
+	for name, value := range access.Headers {
+		req.Header.Set(name, value)
+	}
+	for _, name := range proxyRequestHeaders {
+		if value := header.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+
+	resp, err := proxyClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting content from store: %s", err)
+	}
+
+	// The partial content and the unmet conditions are the client's business
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent, http.StatusNotModified,
+		http.StatusPreconditionFailed, http.StatusRequestedRangeNotSatisfiable:
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	return nil, fmt.Errorf("Store refused content: %s - %s", resp.Status, proxyErrorBody(resp))
+}
+
+// CopyProxyHeaders sets the headers of the store response to be passed
+// back to the client
+func CopyProxyHeaders(dst http.Header, resp *http.Response) {
+	for _, name := range proxyResponseHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			dst.Set(name, value)
+		}
+	}
+}
+
+// proxyErrorBody is the beginning of the error reply of the store for the log
+func proxyErrorBody(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	return string(body)
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+const (
+	rekeyPageSize = 100
+)
+
+// RekeyFileObjects moves the objects of the other layouts to the keys of the given
+// one. The object is copied first, then the row is updated and the old object
+// is removed so the file stays reachable if the run is interrupted. The files
+// not yet uploaded or with the upload in progress are skipped as the clients
+// may hold the urls of the old keys. The files recorded before the object key
+// was tracked have the key of the untracked layout. It returns the number of moved files,
+// in dry run mode the number of the files to be moved. The run stops between
+// the files once the context is done, the progress is the number of the files
+// moved so far if reported. The object shared by several files is moved
+// once for all of them.
+func RekeyFileObjects(ctx context.Context, layout int, dryRun bool, progress JobProgress) (int64, error) {
+	if layout < ObjectKeyLayoutLegacy || layout > ObjectKeyLayoutHashedPrefix {
+		return 0, fmt.Errorf("Invalid object key layout: %d", layout)
+	}
+
+	r, err := db.NewRepository()
+	if err != nil {
+		return 0, fmt.Errorf("Error creating db repository: %s", err)
+	}
+	defer r.Close()
+
+	var (
+		moved int64
+		after uint
+	)
+	for {
+		fss, count, err := r.ReadByKeyLayout(layout, after, rekeyPageSize)
+		if err != nil {
+			return moved, fmt.Errorf("Error reading db repository: %s", err)
+		}
+		if count == 0 {
+			break
+		}
+		after = fss[count-1].ID
+
+		for _, fs := range fss {
+			if ctx.Err() != nil {
+				return moved, ctx.Err()
+			}
+			if progress != nil {
+				progress(moved, 0)
+			}
+			if fs.LegalHold {
+				Log.Info("Skipping file under legal hold: " + fmt.Sprintf("%d", fs.ID))
+				continue
+			}
+			if fs.Status == "N" || fs.Status == "M" {
+				Log.Info("Skipping file not uploaded: " + fmt.Sprintf("%d", fs.ID))
+				continue
+			}
+			if dryRun {
+				Log.Info("Would rekey file: " + fmt.Sprintf("%d", fs.ID) + " object: " + fs.Object)
+				moved++
+				continue
+			}
+
+			err = rekeyFileObject(r, fs, layout)
+			if err != nil {
+				return moved, err
+			}
+			moved++
+		}
+	}
+
+	return moved, nil
+}
+
+// rekeyFileObject moves single object to the key of the layout, the file read
+// again is skipped if its shared object was moved with the other file already
+func rekeyFileObject(r db.FileStoreRepositoryGORM, fs db.FileStore, layout int) error {
+	fss, _, err := r.ReadByPrimaryKey(fs.ID)
+	if err != nil {
+		return fmt.Errorf("Error reading db repository: %s", err)
+	}
+	fs = fss[0]
+	if fs.KeyLayout == layout {
+		return nil
+	}
+
+	fsrep, err := NewRepository(FileBackend(fs), fs.TenantID, fs.DeviceID, fs.Name, fs.ID)
+	if err != nil {
+		return fmt.Errorf("Error creating store repository: %s", err)
+	}
+
+	// The legacy key of the file recorded before the object key was tracked is
+	// recorded first so the file is told apart from the other legacy ones
+	if fs.Object == "" {
+		location, bucket := fsrep.BucketLocation()
+		err = r.SetBucketLocation(fs.ID, bucket, location, fsrep.ObjectName(), fs.Credentials, ObjectKeyLayoutUntracked)
+		if err != nil {
+			return fmt.Errorf("Error updating db repository: %s", err)
+		}
+		fs.Location, fs.Bucket, fs.Object, fs.KeyLayout = location, bucket, fsrep.ObjectName(), ObjectKeyLayoutUntracked
+	}
+	if fs.UUID == nil {
+		return fmt.Errorf("Missing public id of file %d, the migration of the ids is to be run first", fs.ID)
+	}
+
+	// The object shared with the file under legal hold is kept where it is
+	refs, _, err := r.ReadObjectRefs(fs)
+	if err != nil {
+		return fmt.Errorf("Error reading db repository: %s", err)
+	}
+	for _, ref := range refs {
+		if ref.LegalHold {
+			Log.Info("Skipping file sharing object under legal hold: " + fmt.Sprintf("%d", fs.ID))
+			return nil
+		}
+	}
+
+	object, err := ObjectKey(layout, fs.ID, *fs.UUID, fs.TenantID, fs.DeviceID, fs.Name)
+	if err != nil {
+		return err
+	}
+
+	// The old object is copied and removed by its version, the one of the file
+	// recorded before the versions were tracked is the latest one
+	if fs.VersionID == "" {
+		fs.VersionID, err = fsrep.FileObjectVersion()
+		if err != nil {
+			return err
+		}
+	}
+	fsrep = fsrep.WithVersion(fs.VersionID)
+	err = fsrep.FileObjectCopy(object, fs.CheckSumType, fs.Size, fs.ContentType, fs.Metadata)
+	if err != nil {
+		return fmt.Errorf("Error copying object of file %d: %s", fs.ID, err)
+	}
+
+	// The version of the copy is read back by the repository of its own key
+	moved := fs
+	moved.Object, moved.KeyLayout = object, layout
+	mvrep, err := NewRepository(FileBackend(moved), fs.TenantID, fs.DeviceID, fs.Name, fs.ID)
+	if err != nil {
+		return fmt.Errorf("Error creating store repository: %s", err)
+	}
+	version, err := mvrep.FileObjectVersion()
+	if err != nil {
+		return err
+	}
+
+	err = r.SetSharedObjectKey(fs, object, layout, version)
+	if err != nil {
+		return fmt.Errorf("Error updating db repository: %s", err)
+	}
+
+	err = fsrep.FileObjectDelete()
+	if err != nil {
+		return fmt.Errorf("Error deleting old object of file %d: %s", fs.ID, err)
+	}
+	Log.Info("Rekeyed file: " + fmt.Sprintf("%d", fs.ID) + " object: " + fs.Object + " to: " + object)
+
+	return nil
+}
@@ -2,32 +2,56 @@ package store
 
 import (
 	"fmt"
+	"io"
+	"time"
 
 	. "fs/service/config"
+	"fs/service/db"
 )
 
 // Repository is an interface method to be used by the API to access the data store
 type Repository interface {
 	BucketLocation() (string, string)	
-	FileObjectPresignedGetURL(cksum string, size int64) (string, error)
-	FileObjectPresignedPutURL(cksum string, size int64) (string, error)
-	FileObjectPresignedHeadURL(cksum string, size int64) (string, error)		
+	ObjectName() string
+	ObjectKeyLayout() int
+	Encryption() (string, string)
+	PresignDuration() time.Duration
+	WithPresignDuration(d time.Duration) Repository
+	WithVersion(version string) Repository
+	AssureBucketExist() error
+	FileObjectPresignedGetURL(cksum string, size int64, response ResponseOverrides) (db.FileAccess, error)
+	FileObjectPresignedPutURL(cksumType, cksum string, size int64, contentType string, metadata map[string]string) (db.FileAccess, error)
+	FileObjectPresignedHeadURL(cksum string, size int64) (db.FileAccess, error)
+	FileObjectChecksum(cksumType string) (string, error)
+	FileObjectCopy(object, cksumType string, size int64, contentType string, metadata map[string]string) error
+	FileObjectDelete() error
+	FileObjectLegalHold(on bool) error
+	FileObjectVersion() (string, error)
+	FileObjectReader() (io.ReadCloser, int64, error)
+	FileObjectUpload(body io.Reader, contentType string, metadata map[string]string) error
+	FileObjectMultipartCreate(contentType string, metadata map[string]string) (string, error)
+	FileObjectPresignedPartURL(uploadId string, part, size int64) (db.FileAccess, error)
+	FileObjectMultipartParts(uploadId string) ([]db.FilePart, error)
+	FileObjectMultipartComplete(uploadId string, parts []db.FilePart) error
+	FileObjectMultipartAbort(uploadId string) error
 }
 
 // NewRepository is a dispatch for specific repositories implemented with MINIO
 // or some other technology like AWS S3 API, Google or Azure, supported
 // by MINIO or not but having (possibly) different structure of access crdentials
-// and/or config options.
-func NewRepository(kind, tid, did, name string, id uint) (Repository, error) {
-	Log.Debug("Producing repository of kind: " + kind +
-		" for object: " + objectKeyName(id, tid, did,  name))
+// and/or config options. The backend recorded for the existing object is used
+// to reach it, the one without bucket means a new object with the bucket to be
+// allocated.
+func NewRepository(be Backend, tid, did, name string, id uint) (Repository, error) {
+	Log.Debug("Producing repository of backend: " + be.String() +
+		" for file: " + fmt.Sprintf("%d", id))
 
-	if kind == "awss3" { // AWSS3 uses default AWS config
-		return NewAWSS3RepositoryDefault(tid, did, name, id)
-	} else if kind[0:5] == "awss3" { // Other generic full setup
-		return NewAWSS3RepositoryGeneric(tid, did, name, id)
+	if be.Kind == "awss3" { // AWSS3 uses default AWS config
+		return NewAWSS3RepositoryDefault(be, tid, did, name, id)
+	} else if len(be.Kind) >= 5 && be.Kind[0:5] == "awss3" { // Other generic full setup
+		return NewAWSS3RepositoryGeneric(be, tid, did, name, id)
 	}
 
 	return nil,
-		fmt.Errorf("Invalid kind of repository: %s", kind)
+		fmt.Errorf("Invalid kind of repository: %s", be.Kind)
 }
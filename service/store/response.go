@@ -0,0 +1,111 @@
+package store
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+
+	. "fs/service/config"
+)
+
+// The dispositions of the downloaded content
+const (
+	DispositionInline     = "inline"
+	DispositionAttachment = "attachment"
+)
+
+var (
+	// The cache control directives the client may ask for
+	cacheControlPattern = regexp.MustCompile("^(no-cache|no-store|no-transform|public|private|must-revalidate|immutable|max-age=[0-9]{1,9})$")
+)
+
+// ResponseOverrides are the headers of the GET response the client may ask
+// the store to return instead of the ones stored with the object
+type ResponseOverrides struct {
+	Disposition  string
+	ContentType  string
+	CacheControl string
+}
+
+// ValidateContentType checks the media type is in the allowlist of the config,
+// the parameters like charset are allowed as long as they are well formed
+func ValidateContentType(contentType string) error {
+	if contentType == "" {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("Invalid content type: %s", contentType)
+	}
+	for _, allowed := range Setup.AllowedContentTypes {
+		if mediaType == allowed {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Content type not allowed: %s", mediaType)
+}
+
+// ValidateResponseOverrides checks the overrides against the allowlists
+func ValidateResponseOverrides(o ResponseOverrides) error {
+	switch o.Disposition {
+	case "", DispositionInline, DispositionAttachment:
+	default:
+		return fmt.Errorf("Invalid disposition: %s, expecting: inline, attachment", o.Disposition)
+	}
+
+	err := ValidateContentType(o.ContentType)
+	if err != nil {
+		return err
+	}
+
+	if o.CacheControl != "" {
+		for _, directive := range strings.Split(o.CacheControl, ",") {
+			if !cacheControlPattern.MatchString(strings.TrimSpace(directive)) {
+				return fmt.Errorf("Cache control directive not allowed: %s", directive)
+			}
+		}
+	}
+
+	return nil
+}
+
+// responseParams are the response-* query parameters of the presigned GET
+// used by all stores. The content is downloaded as the attachment with
+// the name of the file unless the inline disposition is requested.
+func responseParams(o ResponseOverrides, filename string) url.Values {
+	params := make(url.Values)
+
+	disposition := o.Disposition
+	if disposition == "" {
+		disposition = DispositionAttachment
+	}
+	params.Set("response-content-disposition", contentDisposition(disposition, filename))
+
+	if o.ContentType != "" {
+		params.Set("response-content-type", o.ContentType)
+	}
+	if o.CacheControl != "" {
+		params.Set("response-cache-control", o.CacheControl)
+	}
+
+	return params
+}
+
+// contentDisposition formats the header with the file name, the name not
+// being plain ASCII is given in the extended form of RFC 6266
+func contentDisposition(disposition, filename string) string {
+	if filename == "" {
+		return disposition
+	}
+
+	value := mime.FormatMediaType(disposition, map[string]string{"filename": filename})
+	if value == "" {
+		return disposition
+	}
+
+	return value
+}
@@ -0,0 +1,302 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// The reasons of the file falling outside the retention rule
+const (
+	RetentionReasonMaxAge   = "max_age"
+	RetentionReasonMaxCount = "max_count"
+	RetentionReasonMaxBytes = "max_bytes"
+)
+
+// RetentionItem is the file to be removed by the retention rule of the
+// category with the reason, as shown by the dry run report
+type RetentionItem struct {
+	ID        string    `json:"id"`
+	DeviceID  string    `json:"device_id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	Category  string    `json:"category,omitempty"`
+	Rule      string    `json:"rule"`
+	Reason    string    `json:"reason"`
+}
+
+// retentionCandidate is the file to be removed with the item describing it
+type retentionCandidate struct {
+	file db.FileStore
+	item RetentionItem
+}
+
+// retentionJobPayload is the tenant whose rules are enforced
+type retentionJobPayload struct {
+	TenantID string `json:"tenant"`
+	DryRun   bool   `json:"dry_run,omitempty"`
+}
+
+// RetentionReason is the first limit of the rule the candidate falls outside,
+// the age is checked first as it does not depend on the other files
+func RetentionReason(rr db.RetentionRule, rc db.RetentionCandidate, now time.Time) string {
+	switch {
+	case rr.MaxAgeDays > 0 && rc.CreatedAt.Before(now.Add(-rr.MaxAge())):
+		return RetentionReasonMaxAge
+	case rr.MaxCount > 0 && rc.DeviceRank > rr.MaxCount:
+		return RetentionReasonMaxCount
+	case rr.MaxBytes > 0 && rc.NewerBytes > rr.MaxBytes:
+		return RetentionReasonMaxBytes
+	}
+
+	return ""
+}
+
+// RetentionReport lists at most the limit of the tenant files to be removed
+// by its rules, the oldest ones of each rule first
+func RetentionReport(r db.FileStoreRepositoryGORM, tid string, limit int) ([]RetentionItem, error) {
+	rcs, err := retentionCandidates(r, tid, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]RetentionItem, len(rcs))
+	for i := range rcs {
+		items[i] = rcs[i].item
+	}
+
+	return items, nil
+}
+
+// retentionCandidates are the files outside the rules of the tenant
+func retentionCandidates(r db.FileStoreRepositoryGORM, tid string, limit int) ([]retentionCandidate, error) {
+	rrs, _, err := r.ReadRetentionRules(tid)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading db repository: %s", err)
+	}
+
+	// The rule with no category covers the categories with no rule
+	var categories []string
+	for _, rr := range rrs {
+		if rr.Category != "" {
+			categories = append(categories, rr.Category)
+		}
+	}
+
+	now := time.Now()
+	var candidates []retentionCandidate
+	for _, rr := range rrs {
+		if len(candidates) >= limit {
+			break
+		}
+		rcs, _, err := r.ReadRetentionCandidates(rr, categories, limit-len(candidates))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading db repository: %s", err)
+		}
+		for _, rc := range rcs {
+			candidates = append(candidates, retentionCandidate{
+				file: rc.FileStore,
+				item: RetentionItem{
+					ID:        *rc.UUID,
+					DeviceID:  rc.DeviceID,
+					Name:      rc.Name,
+					Size:      rc.Size,
+					CreatedAt: rc.CreatedAt,
+					Category:  rc.Metadata["category"],
+					Rule:      rr.Category,
+					Reason:    RetentionReason(rr, rc, now),
+				},
+			})
+		}
+	}
+
+	return candidates, nil
+}
+
+// EnforceRetention removes the records of the tenant files outside its rules
+// and their objects not shared by other files, at most the configured number
+// of them per run. The run stops between the files once the context is done. It returns the number
+// of the files and the bytes removed, in dry run mode the ones to be removed.
+func EnforceRetention(ctx context.Context, tid string, dryRun bool, progress JobProgress) (int64, int64, error) {
+	r, err := db.NewRepository()
+	if err != nil {
+		return 0, 0, fmt.Errorf("Error creating db repository: %s", err)
+	}
+	defer r.Close()
+
+	rcs, err := retentionCandidates(r, tid, Setup.RetentionMaxDeletes)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var removed, bytes, failed int64
+	total := int64(len(rcs))
+	for _, rc := range rcs {
+		if ctx.Err() != nil {
+			return removed, bytes, ctx.Err()
+		}
+		if progress != nil {
+			progress(removed+failed, total)
+		}
+		if dryRun {
+			Log.Info("Would remove file " + rc.item.ID + " of tenant " + tid + " by " + rc.item.Reason)
+			removed++
+			bytes += rc.item.Size
+			continue
+		}
+
+		err = removeFile(r, rc.file)
+		if errors.Is(err, ErrFileLegalHold) {
+			Log.Info("Kept file " + rc.item.ID + " of tenant " + tid + " under legal hold")
+			continue
+		}
+		if err != nil {
+			Log.Error("Error removing file " + rc.item.ID + " of tenant " + tid + " - " + err.Error())
+			failed++
+			continue
+		}
+		Log.Info("Removed file " + rc.item.ID + " of tenant " + tid + " by " + rc.item.Reason)
+		RecordAuditEvents([]db.AuditEvent{{
+			Action:    AuditActionDelete,
+			Principal: AuditPrincipalRetention,
+			TenantID:  tid,
+			DeviceID:  rc.item.DeviceID,
+			FileID:    rc.item.ID,
+			Detail:    rc.item.Rule + ": " + rc.item.Reason,
+		}})
+		removed++
+		bytes += rc.item.Size
+	}
+
+	if failed > 0 {
+		return removed, bytes, fmt.Errorf("Failed to remove %d files of tenant %s", failed, tid)
+	}
+
+	return removed, bytes, nil
+}
+
+// removeFile removes the record of the file and then its object from the store
+// unless the object is still shared by other files. The file is read again just
+// before so that the legal hold set in the meantime is respected. The object
+// left behind by the failed removal is not reachable by any file.
+func removeFile(r db.FileStoreRepositoryGORM, fs db.FileStore) error {
+	fss, _, err := r.ReadByPrimaryKey(fs.ID)
+	if err != nil {
+		return fmt.Errorf("Error reading db repository: %s", err)
+	}
+	fs = fss[0]
+	if fs.LegalHold {
+		return ErrFileLegalHold
+	}
+
+	deleted, refs, err := r.DeleteReference(fs)
+	if err != nil {
+		return fmt.Errorf("Error updating db repository: %s", err)
+	}
+	if deleted == 0 {
+		return ErrFileLegalHold
+	}
+	if fs.Object == "" || refs > 0 {
+		return nil
+	}
+
+	fsrep, err := NewRepository(FileBackend(fs), fs.TenantID, fs.DeviceID, fs.Name, fs.ID)
+	if err != nil {
+		return fmt.Errorf("Error creating store repository: %s", err)
+	}
+	err = fsrep.WithVersion(fs.VersionID).FileObjectDelete()
+	if err != nil {
+		return fmt.Errorf("Error deleting object: %s", err)
+	}
+
+	return nil
+}
+
+// StartRetentionScheduler submits the retention job of each tenant with rules
+// every interval unless the one of the tenant is already pending or running,
+// the jobs are run by the job workers of any replica
+func StartRetentionScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			err := scheduleRetentionJobs()
+			if err != nil {
+				Log.Error("Error scheduling retention jobs - " + err.Error())
+			}
+		}
+	}()
+	Log.Info("Started retention scheduler every " + interval.String())
+}
+
+// scheduleRetentionJobs submits the retention jobs due
+func scheduleRetentionJobs() error {
+	r, err := db.NewRepository()
+	if err != nil {
+		return fmt.Errorf("Error creating db repository: %s", err)
+	}
+	defer r.Close()
+
+	tids, err := r.ReadRetentionTenants()
+	if err != nil {
+		return fmt.Errorf("Error reading db repository: %s", err)
+	}
+	for _, tid := range tids {
+		_, pending, err := r.ReadJobs(JobTypeRetention, tid, JobStatePending, 1)
+		if err != nil {
+			return fmt.Errorf("Error reading db repository: %s", err)
+		}
+		_, running, err := r.ReadJobs(JobTypeRetention, tid, JobStateRunning, 1)
+		if err != nil {
+			return fmt.Errorf("Error reading db repository: %s", err)
+		}
+		if pending+running > 0 {
+			continue
+		}
+
+		payload, _ := json.Marshal(retentionJobPayload{TenantID: tid})
+		js, _, err := r.CreateJob(JobTypeRetention, tid, payload, Setup.JobMaxAttempts)
+		if err != nil {
+			return fmt.Errorf("Error creating job: %s", err)
+		}
+		Log.Info("Submitted retention job " + *js[0].UUID + " of tenant " + tid)
+	}
+
+	return nil
+}
+
+// validateRetentionJob checks the tenant is given
+func validateRetentionJob(payload db.JobPayload) error {
+	var p retentionJobPayload
+	err := json.Unmarshal(payload, &p)
+	if err != nil {
+		return fmt.Errorf("Invalid retention job payload: %s", err)
+	}
+	if p.TenantID == "" {
+		return fmt.Errorf("Invalid retention job payload, empty tenant")
+	}
+
+	return nil
+}
+
+// runRetentionJob enforces the rules of the tenant
+func runRetentionJob(ctx context.Context, job db.Job, progress JobProgress) (interface{}, error) {
+	var p retentionJobPayload
+	err := json.Unmarshal(job.Payload, &p)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid retention job payload: %s", err)
+	}
+
+	removed, bytes, err := EnforceRetention(ctx, p.TenantID, p.DryRun, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"removed": removed, "bytes": bytes, "dry_run": p.DryRun}, nil
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+var (
+	// ErrRevisionNotUploaded refuses the rollback to the revision with no object
+	ErrRevisionNotUploaded = errors.New("Revision is not uploaded")
+)
+
+// RollbackRevision makes the given revision the latest one again. The object
+// of the revision is copied to the new revision in the same bucket, so that
+// the history is kept and the revisions in between are not removed. The copy
+// gets the version of its own where the bucket is versioned.
+func RollbackRevision(r db.FileStoreRepositoryGORM, rev db.FileStore) (db.FileStore, error) {
+	if rev.Status == "N" || rev.Status == "M" || rev.Object == "" {
+		return db.FileStore{}, ErrRevisionNotUploaded
+	}
+
+	fss, _, err := r.Create(rev.TenantID, rev.DeviceID, rev.Name, rev.CheckSumType, rev.CheckSum, rev.Size, rev.ContentType, rev.Metadata)
+	if err != nil {
+		return db.FileStore{}, fmt.Errorf("Error creating db record: %s", err)
+	}
+	fs := fss[0]
+
+	err = copyRevision(r, rev, fs)
+	if err != nil {
+		// The new revision with no object must not become the latest one
+		_, derr := r.DeleteByPrimaryKey(fs.ID)
+		if derr != nil {
+			Log.Error("Error removing revision " + *fs.UUID + " of failed rollback - " + derr.Error())
+		}
+		return db.FileStore{}, err
+	}
+
+	fss, _, err = r.ReadByPrimaryKey(fs.ID)
+	if err != nil {
+		return db.FileStore{}, fmt.Errorf("Error reading db repository: %s", err)
+	}
+	Log.Info("Rolled back file " + rev.TenantID + "/" + rev.DeviceID + "/" + rev.Name +
+		fmt.Sprintf(" to revision %d as revision %d", rev.Revision, fss[0].Revision))
+
+	return fss[0], nil
+}
+
+// copyRevision copies the object of the revision to the key of the new one
+// with the same layout and records its location. The revision recorded before
+// the layouts were tracked is copied to the legacy layout, the key of its own
+// layout may be shared with the other revisions.
+func copyRevision(r db.FileStoreRepositoryGORM, rev, fs db.FileStore) error {
+	fsrep, err := NewRepository(FileBackend(rev), rev.TenantID, rev.DeviceID, rev.Name, rev.ID)
+	if err != nil {
+		return fmt.Errorf("Error creating store repository: %s", err)
+	}
+
+	layout := FileBackend(rev).Layout
+	if layout == ObjectKeyLayoutUntracked {
+		layout = ObjectKeyLayoutLegacy
+	}
+	object, err := ObjectKey(layout, fs.ID, *fs.UUID, fs.TenantID, fs.DeviceID, fs.Name)
+	if err != nil {
+		return err
+	}
+
+	err = fsrep.WithVersion(rev.VersionID).FileObjectCopy(object, rev.CheckSumType, rev.Size, rev.ContentType, rev.Metadata)
+	if err != nil {
+		return fmt.Errorf("Error copying object of revision %d: %s", rev.Revision, err)
+	}
+
+	err = r.SetBucketLocation(fs.ID, rev.Bucket, rev.Location, object, rev.Credentials, layout)
+	if err != nil {
+		return fmt.Errorf("Error updating db repository: %s", err)
+	}
+	err = r.SetEncryption(fs.ID, rev.Encryption, rev.EncryptionKeyID)
+	if err != nil {
+		return fmt.Errorf("Error updating db repository: %s", err)
+	}
+	err = r.UpdateStatusById(fs.ID, rev.Status, rev.VerifiedCheckSum)
+	if err != nil {
+		return fmt.Errorf("Error updating db repository: %s", err)
+	}
+
+	// The version of the copy is read back by the repository of its own key
+	fs.Bucket, fs.Location, fs.Object, fs.Credentials, fs.KeyLayout = rev.Bucket, rev.Location, object, rev.Credentials, layout
+	fs.Encryption, fs.EncryptionKeyID = rev.Encryption, rev.EncryptionKeyID
+	fsrep, err = NewRepository(FileBackend(fs), fs.TenantID, fs.DeviceID, fs.Name, fs.ID)
+	if err != nil {
+		return fmt.Errorf("Error creating store repository: %s", err)
+	}
+	version, err := fsrep.FileObjectVersion()
+	if err != nil {
+		return err
+	}
+	if version != "" {
+		err = r.SetVersionID(fs.ID, version)
+		if err != nil {
+			return fmt.Errorf("Error updating db repository: %s", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package store_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+func TestRecordAuditEventsSink(t *testing.T) {
+	InitTestLogger()
+	setTestConfig(t)
+	t.Setenv("POSTGRES_USER", "")
+	sink := filepath.Join(t.TempDir(), "audit.jsonl")
+	Setup.AuditSink = sink
+
+	// The events are appended to the sink in order even without the db repository
+	store.RecordAuditEvents([]db.AuditEvent{
+		{Action: store.AuditActionPresign, Method: "get", Principal: "alice", FileID: "f1", Status: 200},
+		{Action: store.AuditActionDelete, Principal: store.AuditPrincipalRetention, FileID: "f2"},
+	})
+	store.RecordAuditEvents([]db.AuditEvent{
+		{Action: store.AuditActionAdmin, Principal: "bob", TenantID: "t1"},
+	})
+	store.FlushAuditEvents()
+
+	f, err := os.Open(sink)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	var aes []db.AuditEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ae db.AuditEvent
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &ae))
+		aes = append(aes, ae)
+	}
+	assert.Len(t, aes, 3)
+	assert.Equal(t, "get", aes[0].Method)
+	assert.Equal(t, store.AuditPrincipalRetention, aes[1].Principal)
+	assert.Equal(t, "t1", aes[2].TenantID)
+}
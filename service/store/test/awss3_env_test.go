@@ -10,7 +10,7 @@ import (
 	"fs/service/store"
 )
 
-// Mock setup
+// Mock setup, the previous one is restored once the test is done
 func setTestConfig(t *testing.T) {
 	s, err := NewSetupValueSet([]byte(""))
 	if err != nil {
@@ -21,7 +21,9 @@ func setTestConfig(t *testing.T) {
 	}
 
 	s.UseFileStore = "testing"
-	
+
+	previous := Setup
+	t.Cleanup(func() { Setup = previous })
 	Setup = s
 }
 
@@ -0,0 +1,103 @@
+package store_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "fs/service/config"
+	"fs/service/store"
+)
+
+func newTestingRepository(t *testing.T) store.Repository {
+	InitTestLogger()
+	setTestConfig(t)
+	Setup.LogLevel = "debug"
+	Setup.AllowedContentTypes = []string{"text/plain"}
+	t.Setenv("AWSS3TESTING_HOST", "localhost")
+	t.Setenv("AWSS3TESTING_PORT", "9000")
+	t.Setenv("AWSS3TESTING_BUCKET_NAME", "testbucket")
+	t.Setenv("AWSS3TESTING_ACCESS_KEY_ID", "testaccesskeyid")
+	t.Setenv("AWSS3TESTING_SECRET_ACCESS_KEY", "testsecretaccesskey")
+	t.Setenv("AWSS3TESTING_REGION", "us-east-1")
+	t.Setenv("AWSS3TESTING_SECURE", "false")
+	t.Setenv("AWSS3TESTING_PRESIGN_DURATION_MIN", "15")
+
+	fsrep, err := store.NewRepository(store.Backend{
+		Kind:   "awss3testing",
+		Bucket: "testbucket",
+		Layout: store.ObjectKeyLayoutTenantDevice,
+		UUID:   "0b5e5c1e-4a3f-4d2b-9c6e-2f1a7d8e9b10",
+	}, "t1", "d1", "file.txt", 1)
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+
+	return fsrep
+}
+
+func TestAWSS3RepositoryAccess(t *testing.T) {
+	fsrep := newTestingRepository(t)
+
+	t.Run("describes GET access", func(t *testing.T) {
+		access, err := fsrep.FileObjectPresignedGetURL("", 0, store.ResponseOverrides{Disposition: store.DispositionInline})
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodGet, access.Method)
+		assert.Contains(t, access.URL, "X-Amz-Expires=900")
+		assert.Contains(t, access.URL, "response-content-disposition=inline")
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), *access.ExpiresAt, time.Minute)
+		assert.Empty(t, access.Fields)
+	})
+
+	t.Run("describes HEAD access", func(t *testing.T) {
+		access, err := fsrep.FileObjectPresignedHeadURL("", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodHead, access.Method)
+	})
+
+	t.Run("describes PUT access with signed headers", func(t *testing.T) {
+		access, err := fsrep.FileObjectPresignedPutURL("SHA256", strings.Repeat("ab", 32), 10, "text/plain", map[string]string{"kind": "log"})
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodPut, access.Method)
+		assert.Equal(t, "text/plain", access.Headers["content-type"])
+		assert.Equal(t, "10", access.Headers["content-length"])
+		assert.Equal(t, "log", access.Headers["x-amz-meta-kind"])
+		assert.NotContains(t, access.Headers, "host")
+	})
+
+	t.Run("describes part PUT access", func(t *testing.T) {
+		access, err := fsrep.FileObjectPresignedPartURL("upload", 2, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodPut, access.Method)
+		assert.Contains(t, access.URL, "partNumber=2")
+	})
+
+	t.Run("signs with the validity given", func(t *testing.T) {
+		access, err := fsrep.WithPresignDuration(2*time.Hour).FileObjectPresignedGetURL("", 0, store.ResponseOverrides{})
+		assert.NoError(t, err)
+		assert.Contains(t, access.URL, "X-Amz-Expires=7200")
+		assert.WithinDuration(t, time.Now().Add(2*time.Hour), *access.ExpiresAt, time.Minute)
+		assert.Equal(t, 15*time.Minute, fsrep.PresignDuration())
+	})
+}
+
+func TestAWSS3RepositoryNoDebug(t *testing.T) {
+	InitTestLogger()
+	setTestConfig(t)
+	Setup.LogLevel = "info"
+	t.Setenv("AWSS3NODEBUG_HOST", "localhost")
+	t.Setenv("AWSS3NODEBUG_PORT", "9000")
+	t.Setenv("AWSS3NODEBUG_BUCKET_NAME", "testbucket")
+	t.Setenv("AWSS3NODEBUG_ACCESS_KEY_ID", "testaccesskeyid")
+	t.Setenv("AWSS3NODEBUG_SECRET_ACCESS_KEY", "testsecretaccesskey")
+	t.Setenv("AWSS3NODEBUG_REGION", "us-east-1")
+	t.Setenv("AWSS3NODEBUG_SECURE", "false")
+	t.Setenv("AWSS3NODEBUG_PRESIGN_DURATION_MIN", "15")
+
+	// The session of the kind not seen before is created with no AWS logging
+	_, err := store.NewRepository(store.Backend{Kind: "awss3nodebug", Bucket: "testbucket"}, "t1", "d1", "file.txt", 1)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,74 @@
+package store_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+func TestTenantBackend(t *testing.T) {
+	setTestConfig(t)
+	Setup.UseFileStore = "awss3"
+	Setup.ObjectKeyLayout = store.ObjectKeyLayoutHashedPrefix
+
+	t.Run("uses default store without mapping", func(t *testing.T) {
+		assert.Equal(t,
+			store.Backend{Kind: "awss3", Layout: store.ObjectKeyLayoutHashedPrefix},
+			store.TenantBackend(nil))
+	})
+
+	t.Run("uses store mapped to tenant", func(t *testing.T) {
+		tss := []db.TenantStore{{
+			TenantID:       "t1",
+			Kind:           "awss3minio",
+			Bucket:         "t1-bucket",
+			CredentialsRef: "t1",
+		}}
+		assert.Equal(t,
+			store.Backend{Kind: "awss3minio", Bucket: "t1-bucket", Credentials: "t1", Layout: store.ObjectKeyLayoutHashedPrefix},
+			store.TenantBackend(tss))
+	})
+}
+
+func TestFileBackend(t *testing.T) {
+	setTestConfig(t)
+	Setup.UseFileStore = "awss3"
+
+	t.Run("uses backend recorded with file", func(t *testing.T) {
+		fs := db.FileStore{Location: "awss3minio", Bucket: "b", Credentials: "t1", Object: "t/d/o", KeyLayout: 2}
+		assert.Equal(t,
+			store.Backend{Kind: "awss3minio", Bucket: "b", Credentials: "t1", Object: "t/d/o", Layout: 2},
+			store.FileBackend(fs))
+	})
+
+	t.Run("uses default store and untracked layout for file without location", func(t *testing.T) {
+		fs := db.FileStore{Bucket: "b"}
+		assert.Equal(t,
+			store.Backend{Kind: "awss3", Bucket: "b", Layout: store.ObjectKeyLayoutUntracked},
+			store.FileBackend(fs))
+	})
+}
+
+func TestLoadCredentialsRepositoryEnv(t *testing.T) {
+	t.Run("fails without keys", func(t *testing.T) {
+		err, _, _ := store.LoadCredentialsRepositoryEnv("missing")
+		assert.Error(t, err)
+	})
+
+	t.Run("loads keys by reference", func(t *testing.T) {
+		os.Setenv("T1_ACCESS_KEY_ID", "id")
+		os.Setenv("T1_SECRET_ACCESS_KEY", "secret")
+		defer os.Unsetenv("T1_ACCESS_KEY_ID")
+		defer os.Unsetenv("T1_SECRET_ACCESS_KEY")
+
+		err, accessKeyID, secretAccessKey := store.LoadCredentialsRepositoryEnv("t1")
+		assert.NoError(t, err)
+		assert.Equal(t, "id", accessKeyID)
+		assert.Equal(t, "secret", secretAccessKey)
+	})
+}
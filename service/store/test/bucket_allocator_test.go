@@ -0,0 +1,36 @@
+package store_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"fs/service/store"
+)
+
+func TestBucketName(t *testing.T) {
+	t.Run("keeps base bucket for space of one", func(t *testing.T) {
+		assert.Equal(t, "base", store.BucketName("base", 1, "tenant"))
+		assert.Equal(t, "base", store.BucketName("base", 0, "tenant"))
+	})
+
+	t.Run("allocates stable bucket for shard key", func(t *testing.T) {
+		bucket := store.BucketName("base", 10, "tenant")
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, bucket, store.BucketName("base", 10, "tenant"))
+		}
+	})
+
+	t.Run("spreads shard keys over bucket space", func(t *testing.T) {
+		buckets := make(map[string]int)
+		for i := 0; i < 1000; i++ {
+			buckets[store.BucketName("base", 10, fmt.Sprintf("%d", i))]++
+		}
+
+		assert.Equal(t, 10, len(buckets))
+		for i := 0; i < 10; i++ {
+			assert.Greater(t, buckets[fmt.Sprintf("base-%d", i)], 50)
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package store_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"fs/service/store"
+)
+
+func bundleEntries(contents map[string]string, names ...string) []store.BundleEntry {
+	var entries []store.BundleEntry
+	for _, name := range names {
+		content := contents[name]
+		entries = append(entries, store.BundleEntry{
+			Name:    name,
+			ModTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Open: func() (io.ReadCloser, int64, error) {
+				return io.NopCloser(strings.NewReader(content)), int64(len(content)), nil
+			},
+		})
+	}
+
+	return entries
+}
+
+func TestValidateBundleFormat(t *testing.T) {
+	assert.NoError(t, store.ValidateBundleFormat(store.BundleFormatZip))
+	assert.NoError(t, store.ValidateBundleFormat(store.BundleFormatTarGz))
+	assert.Error(t, store.ValidateBundleFormat("rar"))
+	assert.Error(t, store.ValidateBundleFormat(""))
+}
+
+func TestWriteBundleZip(t *testing.T) {
+	contents := map[string]string{"dev1/app.log": "first", "dev2/sys.log": "second"}
+	var buf bytes.Buffer
+	var done []int64
+	err := store.WriteBundle(&buf, store.BundleFormatZip,
+		bundleEntries(contents, "dev1/app.log", "dev2/sys.log", "dev1/app.log"),
+		func(n int64) { done = append(done, n) })
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, done)
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	got := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		got[f.Name] = string(b)
+	}
+	assert.Equal(t, map[string]string{
+		"dev1/app.log":   "first",
+		"dev2/sys.log":   "second",
+		"dev1/app-2.log": "first",
+	}, got)
+}
+
+func TestWriteBundleTarGz(t *testing.T) {
+	contents := map[string]string{"dev1/app.log": "first", "../etc/passwd": "second"}
+	var buf bytes.Buffer
+	err := store.WriteBundle(&buf, store.BundleFormatTarGz,
+		bundleEntries(contents, "dev1/app.log", "../etc/passwd"), nil)
+	assert.NoError(t, err)
+
+	gr, err := gzip.NewReader(&buf)
+	assert.NoError(t, err)
+	tr := tar.NewReader(gr)
+	got := make(map[string]string)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		b, _ := io.ReadAll(tr)
+		got[h.Name] = string(b)
+	}
+	assert.Equal(t, map[string]string{
+		"dev1/app.log": "first",
+		"etc/passwd":   "second",
+	}, got)
+}
+
+func TestWriteBundleOpenError(t *testing.T) {
+	entries := []store.BundleEntry{{
+		Name: "dev1/app.log",
+		Open: func() (io.ReadCloser, int64, error) {
+			return nil, 0, fmt.Errorf("no such object")
+		},
+	}}
+	err := store.WriteBundle(io.Discard, store.BundleFormatZip, entries, nil)
+	assert.ErrorContains(t, err, "no such object")
+}
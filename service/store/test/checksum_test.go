@@ -0,0 +1,82 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"fs/service/db"
+	"fs/service/store"
+)
+
+func TestValidateChecksumType(t *testing.T) {
+	assert.NoError(t, store.ValidateChecksumType("crc32c"))
+	assert.Error(t, store.ValidateChecksumType("FOO"))
+	assert.Error(t, store.ValidateChecksumType(""))
+}
+
+func TestVerifyFileMultipart(t *testing.T) {
+	// The object assembled from parts is not verified, the store is not asked
+	status, verified, err := store.VerifyFile(nil, db.FileStore{PartCount: 2, CheckSumType: "SHA256", CheckSum: "abc"})
+	assert.NoError(t, err)
+	assert.Equal(t, "U", status)
+	assert.Empty(t, verified)
+}
+
+func TestChecksumBase64(t *testing.T) {
+	t.Run("detect unknown checksum type", func(t *testing.T) {
+		_, err := store.ChecksumBase64("SHA512", "abc")
+		if err == nil {
+			t.Fatalf("Error accepting unknown checksum type")
+		}
+	})
+
+	t.Run("detect invalid checksum length", func(t *testing.T) {
+		_, err := store.ChecksumBase64("SHA1", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+		if err == nil {
+			t.Fatalf("Error accepting SHA256 digest as SHA1")
+		}
+	})
+
+	t.Run("converts hex to base64", func(t *testing.T) {
+		sum, err := store.ChecksumBase64("SHA256", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+		if err != nil {
+			t.Fatalf("Error converting checksum: %s", err.Error())
+		}
+
+		assert.Equal(t, "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=", sum)
+	})
+
+	t.Run("accepts base64 of any supported type", func(t *testing.T) {
+		for cksumType, cksum := range map[string]string{
+			"sha256": "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+			"SHA1":   "2jmj7l5rSw0yVb/vlWAYkK/YBwk=",
+			"CRC32C": "AAAAAA==",
+			"CRC32":  "AAAAAA==",
+			"MD5":    "1B2M2Y8AsgTpgAmY7PhCfg==",
+		} {
+			sum, err := store.ChecksumBase64(cksumType, cksum)
+			if err != nil {
+				t.Fatalf("Error converting checksum: %s", err.Error())
+			}
+
+			assert.Equal(t, cksum, sum)
+		}
+	})
+
+	t.Run("gives the same base64 of any encoding", func(t *testing.T) {
+		for _, cksum := range []string{"00000000", "AAAAAA==", "AAAAAB=="} {
+			sum, err := store.ChecksumBase64("CRC32", cksum)
+			if err != nil {
+				t.Fatalf("Error converting checksum: %s", err.Error())
+			}
+
+			assert.Equal(t, "AAAAAA==", sum)
+		}
+	})
+
+	t.Run("maps checksum type to header", func(t *testing.T) {
+		assert.Equal(t, "X-Amz-Checksum-Crc32c", store.ChecksumHeader("crc32c"))
+		assert.Equal(t, "Content-Md5", store.ChecksumHeader("MD5"))
+	})
+}
@@ -0,0 +1,37 @@
+package store_test
+
+import (
+	"os"
+	"testing"
+
+	. "fs/service/config"
+	"fs/service/db"
+)
+
+// newTestingDB opens the db repository of the POSTGRES_* env, the test is
+// skipped when there is no db to run against
+func newTestingDB(t *testing.T) db.FileStoreRepositoryGORM {
+	if os.Getenv("POSTGRES_HOST") == "" {
+		t.Skip("No db, POSTGRES_HOST not set")
+	}
+	InitTestLogger()
+	setTestConfig(t)
+
+	r, err := db.NewRepository()
+	if err != nil {
+		t.Fatalf("Error creating db repository: %s", err)
+	}
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+// newTestingTenant is the tenant of the test not seen by the other runs
+func newTestingTenant(t *testing.T) string {
+	uuid, err := db.NewUUID()
+	if err != nil {
+		t.Fatalf("Error generating tenant: %s", err)
+	}
+
+	return "test-" + uuid
+}
@@ -0,0 +1,120 @@
+package store_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"fs/service/db"
+)
+
+func TestFileAvailable(t *testing.T) {
+	fs := db.FileStore{DeviceID: "d1"}
+
+	t.Run("requires device", func(t *testing.T) {
+		assert.False(t, db.FileAvailable(fs, "", nil, nil))
+	})
+
+	t.Run("available to owner", func(t *testing.T) {
+		assert.True(t, db.FileAvailable(fs, "d1", []uint{1}, nil))
+	})
+
+	t.Run("available tenant wide without groups", func(t *testing.T) {
+		assert.True(t, db.FileAvailable(fs, "d2", nil, nil))
+	})
+
+	t.Run("available to members of assigned groups only", func(t *testing.T) {
+		assert.True(t, db.FileAvailable(fs, "d2", []uint{1, 2}, []uint{2}))
+		assert.False(t, db.FileAvailable(fs, "d2", []uint{1, 2}, []uint{3}))
+		assert.False(t, db.FileAvailable(fs, "d2", []uint{1, 2}, nil))
+	})
+}
+
+func TestReadAvailableFiles(t *testing.T) {
+	r := newTestingDB(t)
+	tid := newTestingTenant(t)
+
+	create := func(did, name string) db.FileStore {
+		fss, _, err := r.Create(tid, did, name, "", "", 1, "", nil)
+		if err != nil {
+			t.Fatalf("Error creating file: %s", err)
+		}
+		return fss[0]
+	}
+	group := func(name, member string) string {
+		dgs, _, err := r.CreateGroup(tid, name)
+		if err != nil {
+			t.Fatalf("Error creating group: %s", err)
+		}
+		gid := strconv.FormatUint(uint64(dgs[0].ID), 10)
+		t.Cleanup(func() { r.DeleteGroupById(gid) })
+		if member != "" {
+			_, _, err = r.AddGroupMember(gid, member)
+			if err != nil {
+				t.Fatalf("Error adding group member: %s", err)
+			}
+		}
+		return gid
+	}
+	assign := func(fs db.FileStore, gid string) {
+		_, _, err := r.AssignFileToGroup(*fs.UUID, gid)
+		if err != nil {
+			t.Fatalf("Error assigning file: %s", err)
+		}
+	}
+
+	owned := create("d1", "owned")
+	assign(owned, group("other", "d3"))
+	unassigned := create("d2", "unassigned")
+	member := create("d2", "member")
+	assign(member, group("ring", "d1"))
+	other := create("d2", "other")
+	assign(other, group("canary", "d2"))
+
+	fss, _, err := r.ReadAvailableFiles(tid, "d1")
+	assert.NoError(t, err)
+	var ids []string
+	for _, fs := range fss {
+		ids = append(ids, *fs.UUID)
+	}
+	assert.ElementsMatch(t, []string{*owned.UUID, *unassigned.UUID, *member.UUID}, ids)
+
+	// The read agrees with the check of each file
+	for fs, expected := range map[*db.FileStore]bool{&owned: true, &unassigned: true, &member: true, &other: false} {
+		ok, err := r.IsFileAvailable(*fs, "d1")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ok, fs.Name)
+	}
+}
+
+func TestDeleteGroupById(t *testing.T) {
+	r := newTestingDB(t)
+	tid := newTestingTenant(t)
+
+	fss, _, err := r.Create(tid, "d1", "file", "", "", 1, "", nil)
+	assert.NoError(t, err)
+	dgs, _, err := r.CreateGroup(tid, "ring")
+	assert.NoError(t, err)
+	gid := strconv.FormatUint(uint64(dgs[0].ID), 10)
+	_, _, err = r.AddGroupMember(gid, "d2")
+	assert.NoError(t, err)
+	_, _, err = r.AssignFileToGroup(*fss[0].UUID, gid)
+	assert.NoError(t, err)
+
+	count, err := r.DeleteGroupById(gid)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	_, count, err = r.ReadGroupMembers(gid)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+	_, count, err = r.ReadFileGroupAssignments(*fss[0].UUID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// The name of the removed group is free again
+	_, count, err = r.CreateGroup(tid, "ring")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
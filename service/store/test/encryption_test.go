@@ -0,0 +1,73 @@
+package store_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"fs/service/store"
+)
+
+func TestTenantEncryptionKey(t *testing.T) {
+	secret := []byte("secret")
+
+	t.Run("derives AES256 key", func(t *testing.T) {
+		assert.Equal(t, 32, len(store.TenantEncryptionKey(secret, "t1")))
+	})
+
+	t.Run("derives stable key of tenant", func(t *testing.T) {
+		assert.Equal(t,
+			store.TenantEncryptionKey(secret, "t1"),
+			store.TenantEncryptionKey(secret, "t1"))
+	})
+
+	t.Run("derives other key for other tenant or secret", func(t *testing.T) {
+		assert.NotEqual(t,
+			store.TenantEncryptionKey(secret, "t1"),
+			store.TenantEncryptionKey(secret, "t2"))
+		assert.NotEqual(t,
+			store.TenantEncryptionKey(secret, "t1"),
+			store.TenantEncryptionKey([]byte("other"), "t1"))
+	})
+}
+
+func TestLoadEncryptionRepositoryEnv(t *testing.T) {
+	defer os.Unsetenv("SSETEST_ENCRYPTION")
+	defer os.Unsetenv("SSETEST_SSEC_KEY_FILE")
+
+	t.Run("defaults to no encryption", func(t *testing.T) {
+		err, mode, _, _ := store.LoadEncryptionRepositoryEnv("ssetest")
+		assert.NoError(t, err)
+		assert.Equal(t, "", mode)
+
+		os.Setenv("SSETEST_ENCRYPTION", "none")
+		err, mode, _, _ = store.LoadEncryptionRepositoryEnv("ssetest")
+		assert.NoError(t, err)
+		assert.Equal(t, "", mode)
+	})
+
+	t.Run("loads encryption mode", func(t *testing.T) {
+		os.Setenv("SSETEST_ENCRYPTION", "sse-kms")
+		err, mode, _, _ := store.LoadEncryptionRepositoryEnv("ssetest")
+		assert.NoError(t, err)
+		assert.Equal(t, store.EncryptionSSEKMS, mode)
+	})
+
+	t.Run("fails for unknown mode", func(t *testing.T) {
+		os.Setenv("SSETEST_ENCRYPTION", "rot13")
+		err, _, _, _ := store.LoadEncryptionRepositoryEnv("ssetest")
+		assert.Error(t, err)
+	})
+
+	t.Run("fails for SSE-C without key file", func(t *testing.T) {
+		os.Setenv("SSETEST_ENCRYPTION", "SSE-C")
+		err, _, _, _ := store.LoadEncryptionRepositoryEnv("ssetest")
+		assert.Error(t, err)
+
+		os.Setenv("SSETEST_SSEC_KEY_FILE", "ssetest.key")
+		err, _, _, keyFile := store.LoadEncryptionRepositoryEnv("ssetest")
+		assert.NoError(t, err)
+		assert.Equal(t, "ssetest.key", keyFile)
+	})
+}
@@ -0,0 +1,60 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+func TestTenantPresignLimits(t *testing.T) {
+	setTestConfig(t)
+	Setup.PresignLimits, _ = ParsePresignLimits("get=60-3600,put=60-86400")
+
+	t.Run("uses configured limits without tenant mapping", func(t *testing.T) {
+		limits, err := store.TenantPresignLimits(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, Setup.PresignLimits, limits)
+	})
+
+	t.Run("overrides the methods given by tenant", func(t *testing.T) {
+		limits, err := store.TenantPresignLimits([]db.TenantStore{{TenantID: "t1", PresignLimits: "put=300-604800"}})
+		assert.NoError(t, err)
+		assert.Equal(t, PresignLimit{Min: time.Minute, Max: time.Hour}, limits["get"])
+		assert.Equal(t, PresignLimit{Min: 5 * time.Minute, Max: 7 * 24 * time.Hour}, limits["put"])
+		assert.Equal(t, PresignLimit{Min: time.Minute, Max: 24 * time.Hour}, Setup.PresignLimits["put"])
+	})
+
+	t.Run("rejects invalid tenant limits", func(t *testing.T) {
+		for _, val := range []string{"post=1-2", "get=10", "get=0-10", "get=20-10", "put=60-604801", "get=a-b"} {
+			_, err := store.TenantPresignLimits([]db.TenantStore{{TenantID: "t1", PresignLimits: val}})
+			assert.Error(t, err, val)
+		}
+	})
+}
+
+func TestPresignExpiry(t *testing.T) {
+	limits, _ := ParsePresignLimits("get=60-3600,put=300-86400")
+
+	t.Run("uses store validity when not requested", func(t *testing.T) {
+		assert.Equal(t, 15*time.Minute, store.PresignExpiry("get", 0, 15*time.Minute, limits))
+	})
+
+	t.Run("uses requested validity within limits", func(t *testing.T) {
+		assert.Equal(t, 2*time.Hour, store.PresignExpiry("put", 2*time.Hour, 15*time.Minute, limits))
+	})
+
+	t.Run("clamps requested validity to limits of method", func(t *testing.T) {
+		assert.Equal(t, time.Hour, store.PresignExpiry("get", 2*time.Hour, 15*time.Minute, limits))
+		assert.Equal(t, time.Minute, store.PresignExpiry("get", time.Second, 15*time.Minute, limits))
+		assert.Equal(t, 5*time.Minute, store.PresignExpiry("put", 0, time.Minute, limits))
+	})
+
+	t.Run("clamps validity without limits to signature maximum", func(t *testing.T) {
+		assert.Equal(t, MaxPresignDuration, store.PresignExpiry("head", 30*24*time.Hour, 15*time.Minute, limits))
+	})
+}
@@ -0,0 +1,40 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"fs/service/db"
+	"fs/service/store"
+)
+
+func TestJobBackoff(t *testing.T) {
+	base := 30 * time.Second
+	assert.Equal(t, 30*time.Second, store.JobBackoff(1, base))
+	assert.Equal(t, 60*time.Second, store.JobBackoff(2, base))
+	assert.Equal(t, 120*time.Second, store.JobBackoff(3, base))
+	assert.Equal(t, time.Hour, store.JobBackoff(20, base))
+	assert.Equal(t, time.Hour, store.JobBackoff(1, 2*time.Hour))
+}
+
+func TestJobTypes(t *testing.T) {
+	assert.Equal(t, []string{store.JobTypeBundle, store.JobTypeRekey, store.JobTypeRetention}, store.JobTypes())
+}
+
+func TestValidateJob(t *testing.T) {
+	assert.NoError(t, store.ValidateJob(store.JobTypeBundle, db.JobPayload(`{"bundle_id":"0b7a3c52-8f0e-4d5e-9c61-3f2a1b4c5d6e"}`)))
+	assert.Error(t, store.ValidateJob(store.JobTypeBundle, db.JobPayload(`{"bundle_id":"x"}`)))
+	assert.Error(t, store.ValidateJob(store.JobTypeBundle, nil))
+
+	assert.NoError(t, store.ValidateJob(store.JobTypeRekey, nil))
+	assert.NoError(t, store.ValidateJob(store.JobTypeRekey, db.JobPayload(`{"layout":3,"dry_run":true}`)))
+	assert.Error(t, store.ValidateJob(store.JobTypeRekey, db.JobPayload(`{"layout":7}`)))
+	assert.Error(t, store.ValidateJob(store.JobTypeRekey, db.JobPayload(`[1]`)))
+
+	assert.NoError(t, store.ValidateJob(store.JobTypeRetention, db.JobPayload(`{"tenant":"xxx","dry_run":true}`)))
+	assert.Error(t, store.ValidateJob(store.JobTypeRetention, db.JobPayload(`{"dry_run":true}`)))
+
+	assert.Error(t, store.ValidateJob("reconcile", nil))
+}
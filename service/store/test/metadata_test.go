@@ -0,0 +1,45 @@
+package store_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"fs/service/store"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	t.Run("accepts empty metadata", func(t *testing.T) {
+		assert.NoError(t, store.ValidateMetadata(nil))
+	})
+
+	t.Run("accepts valid metadata", func(t *testing.T) {
+		assert.NoError(t, store.ValidateMetadata(map[string]string{
+			"log-type":         "kernel",
+			"firmware_version": "1.2.3",
+			"reason":           "crash at 12:00, reboot",
+		}))
+	})
+
+	t.Run("rejects too many keys", func(t *testing.T) {
+		metadata := make(map[string]string)
+		for i := 0; i <= store.MetadataMaxKeys; i++ {
+			metadata[fmt.Sprintf("k%d", i)] = "v"
+		}
+		assert.Error(t, store.ValidateMetadata(metadata))
+	})
+
+	t.Run("rejects invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", "Upper", "with space", "-dash", "a:b", strings.Repeat("k", store.MetadataMaxKeyLength+1)} {
+			assert.Error(t, store.ValidateMetadata(map[string]string{key: "v"}), key)
+		}
+	})
+
+	t.Run("rejects invalid values", func(t *testing.T) {
+		for _, value := range []string{"new\nline", "zażółć", strings.Repeat("v", store.MetadataMaxValueLength+1)} {
+			assert.Error(t, store.ValidateMetadata(map[string]string{"k": value}), value)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"fs/service/db"
+	"fs/service/store"
+)
+
+func TestMultipartPartSizes(t *testing.T) {
+	t.Run("detect invalid object size", func(t *testing.T) {
+		_, err := store.MultipartPartSizes(0, store.MultipartMinPartSize)
+		if err == nil {
+			t.Fatalf("Error accepting empty object")
+		}
+	})
+
+	t.Run("splits object with remainder in last part", func(t *testing.T) {
+		size := 2*store.MultipartMinPartSize + 123
+		sizes, err := store.MultipartPartSizes(size, store.MultipartMinPartSize)
+		if err != nil {
+			t.Fatalf("Error splitting object: %s", err.Error())
+		}
+
+		assert.Equal(t, []int64{store.MultipartMinPartSize, store.MultipartMinPartSize, 123}, sizes)
+	})
+
+	t.Run("raises part size below minimum", func(t *testing.T) {
+		sizes, err := store.MultipartPartSizes(store.MultipartMinPartSize+1, 1024)
+		if err != nil {
+			t.Fatalf("Error splitting object: %s", err.Error())
+		}
+
+		assert.Equal(t, []int64{store.MultipartMinPartSize, 1}, sizes)
+	})
+
+	t.Run("lowers part size above maximum", func(t *testing.T) {
+		sizes, err := store.MultipartPartSizes(1024, 2*store.MultipartMaxPartSize)
+		if err != nil {
+			t.Fatalf("Error splitting object: %s", err.Error())
+		}
+
+		assert.Equal(t, []int64{1024}, sizes)
+	})
+
+	t.Run("limits number of parts", func(t *testing.T) {
+		size := (store.MultipartMaxParts + 1) * store.MultipartMinPartSize
+		sizes, err := store.MultipartPartSizes(size, store.MultipartMinPartSize)
+		if err != nil {
+			t.Fatalf("Error splitting object: %s", err.Error())
+		}
+
+		assert.LessOrEqual(t, int64(len(sizes)), store.MultipartMaxParts)
+		var total int64
+		for _, s := range sizes {
+			total += s
+		}
+		assert.Equal(t, size, total)
+	})
+}
+
+func TestValidateMultipartParts(t *testing.T) {
+	sizes := []int64{store.MultipartMinPartSize, 123}
+	received := []db.FilePart{
+		{Number: 1, Size: store.MultipartMinPartSize, ETag: `"e1"`},
+		{Number: 2, Size: 123, ETag: `"e2"`},
+	}
+
+	t.Run("accepts received parts", func(t *testing.T) {
+		err := store.ValidateMultipartParts(sizes, []db.FilePart{{Number: 1, ETag: "e1"}, {Number: 2, ETag: `"e2"`}}, received)
+		assert.NoError(t, err)
+	})
+
+	t.Run("detect missing part", func(t *testing.T) {
+		err := store.ValidateMultipartParts(sizes, []db.FilePart{{Number: 1, ETag: "e1"}, {Number: 2, ETag: "e2"}}, received[:1])
+		assert.Error(t, err)
+	})
+
+	t.Run("detect etag mismatch", func(t *testing.T) {
+		err := store.ValidateMultipartParts(sizes, []db.FilePart{{Number: 1, ETag: "e1"}, {Number: 2, ETag: "e3"}}, received)
+		assert.Error(t, err)
+	})
+
+	t.Run("detect size mismatch", func(t *testing.T) {
+		err := store.ValidateMultipartParts([]int64{store.MultipartMinPartSize, 124}, []db.FilePart{{Number: 1, ETag: "e1"}, {Number: 2, ETag: "e2"}}, received)
+		assert.Error(t, err)
+	})
+
+	t.Run("detect wrong number of parts", func(t *testing.T) {
+		err := store.ValidateMultipartParts(sizes, []db.FilePart{{Number: 1, ETag: "e1"}}, received)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,72 @@
+package store_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "fs/service/config"
+	"fs/service/store"
+)
+
+func TestObjectKey(t *testing.T) {
+	setTestConfig(t)
+	Setup.ObjectNamespaceModule = 10
+	uuid := "0b5e5c1e-4a3f-4d2b-9c6e-2f1a7d8e9b10"
+
+	t.Run("names object with legacy layout", func(t *testing.T) {
+		key, err := store.ObjectKey(store.ObjectKeyLayoutLegacy, 123, uuid, "t", "d", "f.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "3-t-d-123-f.txt", key)
+	})
+
+	t.Run("names object recorded before layouts", func(t *testing.T) {
+		key, err := store.ObjectKey(store.ObjectKeyLayoutUntracked, 123, uuid, "t", "d", "f.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "3-t-d-f.txt", key)
+	})
+
+	t.Run("names revisions with colliding ids apart", func(t *testing.T) {
+		// The ids of the same module name the revisions of the same file
+		for _, layout := range []int{store.ObjectKeyLayoutLegacy, store.ObjectKeyLayoutTenantDevice, store.ObjectKeyLayoutHashedPrefix} {
+			key1, err := store.ObjectKey(layout, 3, uuid, "t", "d", "f.txt")
+			assert.NoError(t, err)
+			key2, err := store.ObjectKey(layout, 13, "6c1d2e3f-5a4b-4c3d-8e2f-1a0b9c8d7e6f", "t", "d", "f.txt")
+			assert.NoError(t, err)
+			assert.NotEqual(t, key1, key2, "layout %d", layout)
+		}
+	})
+
+	t.Run("names object with tenant device layout", func(t *testing.T) {
+		key, err := store.ObjectKey(store.ObjectKeyLayoutTenantDevice, 123, uuid, "t", "d", "f.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "t/d/"+uuid, key)
+	})
+
+	t.Run("names object with hashed prefix layout", func(t *testing.T) {
+		key, err := store.ObjectKey(store.ObjectKeyLayoutHashedPrefix, 123, uuid, "t", "d", "f.txt")
+		assert.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile("^[0-9a-f]{2}/[0-9a-f]{2}/"+uuid+"$"), key)
+		assert.NotContains(t, key, "f.txt")
+	})
+
+	t.Run("derives object key from the file", func(t *testing.T) {
+		key1, _ := store.ObjectKey(store.ObjectKeyLayoutHashedPrefix, 123, uuid, "t", "d", "f.txt")
+		key2, _ := store.ObjectKey(store.ObjectKeyLayoutHashedPrefix, 123, uuid, "t", "d", "f.txt")
+		assert.Equal(t, key1, key2)
+
+		key3, _ := store.ObjectKey(store.ObjectKeyLayoutHashedPrefix, 123, "6c1d2e3f-5a4b-4c3d-8e2f-1a0b9c8d7e6f", "t", "d", "f.txt")
+		assert.NotEqual(t, key1, key3)
+	})
+
+	t.Run("fails without public id", func(t *testing.T) {
+		_, err := store.ObjectKey(store.ObjectKeyLayoutTenantDevice, 123, "", "t", "d", "f.txt")
+		assert.Error(t, err)
+	})
+
+	t.Run("fails for unknown layout", func(t *testing.T) {
+		_, err := store.ObjectKey(4, 123, uuid, "t", "d", "f.txt")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,115 @@
+package store_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "fs/service/config"
+	"fs/service/db"
+	"fs/service/store"
+)
+
+func TestProxyUpload(t *testing.T) {
+	setTestConfig(t)
+	Setup.ProxyTimeout = time.Minute
+	content := "proxied content"
+	digest := sha256.Sum256([]byte(content))
+	cksum := hex.EncodeToString(digest[:])
+	sum := base64.StdEncoding.EncodeToString(digest[:])
+
+	var received string
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, header = string(body), r.Header
+		if r.Header.Get("X-Amz-Checksum-Sha256") != sum {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "BadDigest")
+		}
+	}))
+	defer srv.Close()
+	access := db.FileAccess{
+		Method:  http.MethodPut,
+		URL:     srv.URL + "/bucket/object",
+		Headers: map[string]string{"content-type": "text/plain", "content-length": "15"},
+	}
+
+	t.Run("streams content with signed headers", func(t *testing.T) {
+		got, err := store.ProxyUpload(access, strings.NewReader(content), int64(len(content)), "SHA256", cksum)
+		assert.NoError(t, err)
+		assert.Equal(t, sum, got)
+		assert.Equal(t, content, received)
+		assert.Equal(t, "text/plain", header.Get("Content-Type"))
+	})
+
+	t.Run("returns checksum of content refused by store", func(t *testing.T) {
+		other := "tampered content"
+		otherDigest := sha256.Sum256([]byte(other))
+		got, err := store.ProxyUpload(access, strings.NewReader(other), int64(len(other)), "SHA256", "")
+		assert.ErrorContains(t, err, "BadDigest")
+		assert.Equal(t, base64.StdEncoding.EncodeToString(otherDigest[:]), got)
+	})
+
+	t.Run("rejects unknown checksum type", func(t *testing.T) {
+		_, err := store.ProxyUpload(access, strings.NewReader(content), int64(len(content)), "SHA512", "")
+		assert.Error(t, err)
+	})
+}
+
+func TestProxyDownload(t *testing.T) {
+	setTestConfig(t)
+	Setup.ProxyTimeout = time.Minute
+	content := "0123456789"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.ServeContent(w, r, "object", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+	access := db.FileAccess{
+		Method:  http.MethodGet,
+		URL:     srv.URL + "/bucket/object",
+		Headers: map[string]string{"x-amz-server-side-encryption-customer-algorithm": "AES256"},
+	}
+
+	t.Run("passes range of client request", func(t *testing.T) {
+		header := make(http.Header)
+		header.Set("Range", "bytes=2-4")
+		resp, err := store.ProxyDownload(access, header)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		assert.Equal(t, "234", string(body))
+
+		copied := make(http.Header)
+		store.CopyProxyHeaders(copied, resp)
+		assert.Equal(t, "bytes 2-4/10", copied.Get("Content-Range"))
+		assert.Equal(t, "3", copied.Get("Content-Length"))
+	})
+
+	t.Run("passes unsatisfiable range to client", func(t *testing.T) {
+		header := make(http.Header)
+		header.Set("Range", "bytes=20-30")
+		resp, err := store.ProxyDownload(access, header)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, resp.StatusCode)
+	})
+
+	t.Run("fails on store refusal", func(t *testing.T) {
+		_, err := store.ProxyDownload(db.FileAccess{Method: http.MethodGet, URL: access.URL}, make(http.Header))
+		assert.ErrorContains(t, err, "403")
+	})
+}
@@ -0,0 +1,62 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "fs/service/config"
+	"fs/service/store"
+)
+
+func TestValidateContentType(t *testing.T) {
+	setTestConfig(t)
+	Setup.AllowedContentTypes = []string{"application/json", "text/plain"}
+
+	t.Run("accepts empty content type", func(t *testing.T) {
+		assert.NoError(t, store.ValidateContentType(""))
+	})
+
+	t.Run("accepts allowed content types with parameters", func(t *testing.T) {
+		assert.NoError(t, store.ValidateContentType("application/json"))
+		assert.NoError(t, store.ValidateContentType("text/plain; charset=utf-8"))
+		assert.NoError(t, store.ValidateContentType("Text/Plain"))
+	})
+
+	t.Run("rejects malformed and not allowed content types", func(t *testing.T) {
+		for _, ct := range []string{"text/html", "image/svg+xml", "text/plain; charset", "/"} {
+			assert.Error(t, store.ValidateContentType(ct), ct)
+		}
+	})
+}
+
+func TestValidateResponseOverrides(t *testing.T) {
+	setTestConfig(t)
+	Setup.AllowedContentTypes = []string{"application/json", "text/plain"}
+
+	t.Run("accepts no overrides", func(t *testing.T) {
+		assert.NoError(t, store.ValidateResponseOverrides(store.ResponseOverrides{}))
+	})
+
+	t.Run("accepts valid overrides", func(t *testing.T) {
+		assert.NoError(t, store.ValidateResponseOverrides(store.ResponseOverrides{
+			Disposition:  store.DispositionInline,
+			ContentType:  "text/plain",
+			CacheControl: "private, max-age=3600",
+		}))
+	})
+
+	t.Run("rejects invalid disposition", func(t *testing.T) {
+		assert.Error(t, store.ValidateResponseOverrides(store.ResponseOverrides{Disposition: "form-data"}))
+	})
+
+	t.Run("rejects not allowed content type", func(t *testing.T) {
+		assert.Error(t, store.ValidateResponseOverrides(store.ResponseOverrides{ContentType: "text/html"}))
+	})
+
+	t.Run("rejects not allowed cache control", func(t *testing.T) {
+		for _, cc := range []string{"max-age=-1", "s-maxage=10", "no-cache\r\nX-Injected: 1", "private,"} {
+			assert.Error(t, store.ValidateResponseOverrides(store.ResponseOverrides{CacheControl: cc}), cc)
+		}
+	})
+}
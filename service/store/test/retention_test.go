@@ -0,0 +1,30 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"fs/service/db"
+	"fs/service/store"
+)
+
+func TestRetentionReason(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	candidate := func(ageDays int, rank, newerBytes int64) db.RetentionCandidate {
+		rc := db.RetentionCandidate{DeviceRank: rank, NewerBytes: newerBytes}
+		rc.CreatedAt = now.Add(-time.Duration(ageDays) * 24 * time.Hour)
+		return rc
+	}
+
+	rule := db.RetentionRule{MaxAgeDays: 30, MaxCount: 5, MaxBytes: 1000}
+	assert.Equal(t, store.RetentionReasonMaxAge, store.RetentionReason(rule, candidate(31, 6, 2000), now))
+	assert.Equal(t, store.RetentionReasonMaxCount, store.RetentionReason(rule, candidate(1, 6, 2000), now))
+	assert.Equal(t, store.RetentionReasonMaxBytes, store.RetentionReason(rule, candidate(1, 5, 1001), now))
+	assert.Equal(t, "", store.RetentionReason(rule, candidate(1, 5, 1000), now))
+
+	// The zero limits are not applied
+	assert.Equal(t, "", store.RetentionReason(db.RetentionRule{}, candidate(1000, 1000, 1<<40), now))
+	assert.Equal(t, store.RetentionReasonMaxCount, store.RetentionReason(db.RetentionRule{MaxCount: 1}, candidate(1000, 2, 0), now))
+}
@@ -0,0 +1,105 @@
+package store_test
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"fs/service/db"
+	"fs/service/store"
+)
+
+func TestRollbackRevisionNotUploaded(t *testing.T) {
+	var r db.FileStoreRepositoryGORM
+
+	// The revision with no object is refused before anything is written
+	for _, rev := range []db.FileStore{
+		{Status: "N", Object: "a", Revision: 1},
+		{Status: "M", Object: "a", Revision: 1},
+		{Status: "C", Revision: 1},
+	} {
+		_, err := store.RollbackRevision(r, rev)
+		assert.ErrorIs(t, err, store.ErrRevisionNotUploaded)
+	}
+}
+
+func TestCreateRevisionNumbering(t *testing.T) {
+	r := newTestingDB(t)
+	tid := newTestingTenant(t)
+
+	// The files of the same name are numbered one after the other
+	for i := 1; i <= 3; i++ {
+		fss, _, err := r.Create(tid, "dev", "a.txt", "", "", 1, "", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, i, fss[0].Revision)
+	}
+
+	// The other name has the numbering of its own
+	fss, _, err := r.Create(tid, "dev", "b.txt", "", "", 1, "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fss[0].Revision)
+
+	fss, count, err := r.ReadRevisions(*fss[0].UUID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestCreateRevisionConcurrent(t *testing.T) {
+	r := newTestingDB(t)
+	tid := newTestingTenant(t)
+
+	// The advisory lock gives the concurrent creates distinct revisions
+	const n = 8
+	var wg sync.WaitGroup
+	revisions := make(chan int, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fss, _, err := r.Create(tid, "dev", "a.txt", "", "", 1, "", nil)
+			if assert.NoError(t, err) {
+				revisions <- fss[0].Revision
+			}
+		}()
+	}
+	wg.Wait()
+	close(revisions)
+
+	var got []int
+	for rev := range revisions {
+		got = append(got, rev)
+	}
+	sort.Ints(got)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8}, got)
+}
+
+func TestRollbackRevisionNext(t *testing.T) {
+	r := newTestingDB(t)
+	tid := newTestingTenant(t)
+
+	fss, _, err := r.Create(tid, "dev", "a.txt", "", "", 1, "", nil)
+	assert.NoError(t, err)
+	_, _, err = r.Create(tid, "dev", "a.txt", "", "", 1, "", nil)
+	assert.NoError(t, err)
+
+	// The rollback creates the next revision, it is removed when the object
+	// cannot be copied to it
+	rev := fss[0]
+	rev.Status = "C"
+	rev.Object = "no-such-object"
+	rev.Bucket = "no-such-bucket"
+	_, err = store.RollbackRevision(r, rev)
+	assert.Error(t, err)
+
+	fss, count, err := r.ReadRevisions(*rev.UUID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, 2, fss[0].Revision)
+
+	// The revision of the failed rollback is free for the next one
+	fss, _, err = r.Create(tid, "dev", "a.txt", "", "", 1, "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, fss[0].Revision)
+}
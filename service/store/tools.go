@@ -8,11 +8,6 @@ import (
 	. "fs/service/config"	
 )
 
-// objectKey provides uniform way of naming s3 bucket objects
-func objectKeyName(tid, did, name string) string {
-	return fmt.Sprintf("%s-%s-%s", tid, did, name)
-}
-
 // Check existence of all AWS S3 specific env variables prefixed
 // by a selector whic allows to store many configs like localfile,
 // minio, aws s3, etc. and choose one of them by 5prefix of
@@ -24,7 +19,7 @@ func objectKeyName(tid, did, name string) string {
 // - accessKeyID: mandatory, string, AWS credentials
 // - secretAccessKey: mandatory, string, AWS credentials
 // - region: mandatory, string, AWS region
-// - presign: mandatory, integer, duration of presign url validity in days
+// - presign: mandatory, integer, duration of presign url validity in minutes
 // - secure: mandatory, bool, SSL or not
 func loadRepositoryEnv() (err error,
 	endpoint, port, bucket, accessKeyID, secretAccessKey, region, presign, secure string) {
@@ -36,7 +31,7 @@ func loadRepositoryEnv() (err error,
 		arg4   string = fmt.Sprintf("%s_ACCESS_KEY_ID", prefix)
 		arg5   string = fmt.Sprintf("%s_SECRET_ACCESS_KEY", prefix)
 		arg6   string = fmt.Sprintf("%s_REGION", prefix)
-		arg7   string = fmt.Sprintf("%s_PRESIGN_DURATION_DAYS", prefix)
+		arg7   string = fmt.Sprintf("%s_PRESIGN_DURATION_MIN", prefix)
 		arg8   string = fmt.Sprintf("%s_SECURE", prefix)
 	)
 	endpoint, port, bucket, accessKeyID, secretAccessKey, region, presign, secure = os.Getenv(arg1),
@@ -57,8 +52,8 @@ func loadRepositoryEnv() (err error,
 		err = fmt.Errorf("Unknown secret access key from %", arg5)
 	} else if region == "" {
 		err = fmt.Errorf("Unknown region from %", arg6)
-	} else if region == "" {
-		err = fmt.Errorf("Unknown presign duration in days from %", arg7)
+	} else if presign == "" {
+		err = fmt.Errorf("Unknown presign duration in minutes from %s", arg7)
 	} else if secure == "" {
 		err = fmt.Errorf("Unknown secure value %", arg7)
 	}
@@ -7,4 +7,6 @@ mkdir -v testdata testresults
 ./run-test-create-upload.sh
 ./run-test-create-upload-update-read.sh
 ./run-test-create-upload-update.sh
-
+./run-test-create-multipart-upload.sh
+./run-test-download-link.sh
+./run-test-revision-rollback.sh
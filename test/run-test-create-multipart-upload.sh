@@ -0,0 +1,180 @@
+#!/bin/bash
+
+TS=$(date +"%Y%m%d%H%M%S")
+HOST=localhost
+PORT=1234
+HEADER="Content-Type: application/json"
+TENANT=xxx
+DEVICE=yyy
+PART_SIZE=5242880
+PART_ETAG=
+DATA=
+TMP_FILE_NAME=
+
+# shellcheck source=test_tools.sh
+. test_tools.sh
+
+# Upload the part with a given index in the reply saving its etag
+# rv -> PART_ETAG
+upload_test_part() {
+	local fn reply i n size url
+	fn="$1"
+	reply="$2"
+	i="$3"
+	n=$(echo "${reply}" | jq ".data[0].parts[${i}].part_number")
+	size=$(echo "${reply}" | jq ".data[0].parts[${i}].size")
+	url=$(echo "${reply}" | jq ".data[0].parts[${i}].url" | tr -d \")
+	dd bs="${PART_SIZE}" skip=$(( n - 1 )) count=1 <"${fn}" >"${fn}.part" 2>/dev/null
+	if [ "$(stat --format=%s "${fn}.part")" -ne "${size}" ]; then
+		echo "### Error ###"
+		return 1
+	fi
+	echo "Running: curl -X PUT -T ${fn}.part -D - ${url}"
+	PART_ETAG=$(curl -X PUT -T "${fn}.part" -D - "${url}" 2>/dev/null | grep -i '^etag:' | awk '{print $2}' | tr -d '\r')
+	if [ -z "${PART_ETAG}" ]; then
+		echo "### Error ###"
+		return 1
+	fi
+	return 0
+}
+
+# Perform one test, create the file, upload it in parts with the presigned URLs,
+# resume the upload after the first part and complete it using the etags of the parts
+function run_test() {
+	local testno fn rfn status rc reply msg bfn url id parts n i etag etags
+
+	testno="$1"
+
+	# Make a test file with random content bigger than 2 parts
+	fn=$(mktemp "testdata/$(date +"%Y%m%d%H%M%SXXXXXX")")
+	dd bs="${PART_SIZE}" count=2 </dev/urandom >"${fn}"
+	dd bs="${testno}" count=1 </dev/urandom >>"${fn}"
+	bfn="$(basename "${fn}")"
+	make_control_file "${bfn}"
+	DATA="${CONTROL_FILE_NAME}"
+
+	#
+	# Send request to the fs API
+	#
+	url="http://${HOST}:${PORT}/api/v1/files?tenant=${TENANT}&device=${DEVICE}"
+	echo "Running: curl -X POST -H ${HEADER} -d@${DATA} ${url}"
+	reply=$(curl -X POST -H "${HEADER}" -d@"${DATA}" "${url}" 2>/dev/null)
+	rc=$?
+	if [ "${rc}" -ne 0 ]; then
+		echo "### Error ###"
+		return 1
+	fi
+	msg=$(echo "${reply}" | jq)
+	rfn="testresults/results-create-multipart-upload-${TS}-step-1-create.lst"
+	echo "Result: ${rc} -> ${msg}" | tee "${rfn}"
+	status=$(echo "${msg}" | jq '.status')
+	if [ "${status}" != "true" ]; then
+		echo "### Error ###"
+		return 1
+	fi
+	id=$(echo "${reply}" | jq '.data[0].id' | tr -d \")
+
+	#
+	# Start multipart upload getting the presigned URL of each part
+	#
+	url="http://${HOST}:${PORT}/api/v1/files/${id}/upload"
+	echo "Running: curl -X POST -H ${HEADER} -d {\"part_size\": ${PART_SIZE}} ${url}"
+	reply=$(curl -X POST -H "${HEADER}" -d "{\"part_size\": ${PART_SIZE}}" "${url}" 2>/dev/null)
+	rc=$?
+	if [ "${rc}" -ne 0 ]; then
+		echo "### Error ###"
+		return 1
+	fi
+	msg=$(echo "${reply}" | jq)
+	rfn="testresults/results-create-multipart-upload-${TS}-step-2-initiate.lst"
+	echo "Result: ${rc} -> ${msg}" | tee "${rfn}"
+	status=$(echo "${msg}" | jq '.status')
+	if [ "${status}" != "true" ]; then
+		echo "### Error ###"
+		return 1
+	fi
+
+	#
+	# Upload the first part only as if the device rebooted in the middle
+	#
+	upload_test_part "${fn}" "${reply}" 0 || return 1
+
+	#
+	# Resume multipart upload getting fresh presigned URL of the missing parts
+	#
+	url="http://${HOST}:${PORT}/api/v1/files/${id}/upload"
+	echo "Running: curl -X GET -H ${HEADER} ${url}"
+	reply=$(curl -X GET -H "${HEADER}" "${url}" 2>/dev/null)
+	rc=$?
+	if [ "${rc}" -ne 0 ]; then
+		echo "### Error ###"
+		return 1
+	fi
+	msg=$(echo "${reply}" | jq)
+	rfn="testresults/results-create-multipart-upload-${TS}-step-3-resume.lst"
+	echo "Result: ${rc} -> ${msg}" | tee "${rfn}"
+	status=$(echo "${msg}" | jq '.data[0].parts_uploaded')
+	if [ "${status}" != "1" ]; then
+		echo "### Error ###"
+		return 1
+	fi
+
+	#
+	# Upload the missing parts collecting the etags of all parts
+	#
+	parts=$(echo "${reply}" | jq '.data[0].parts | length')
+	etags="[]"
+	for (( i = 0; i < parts; i++ )); do
+		n=$(echo "${reply}" | jq ".data[0].parts[${i}].part_number")
+		etag=$(echo "${reply}" | jq ".data[0].parts[${i}].etag // empty")
+		if [ -z "${etag}" ]; then
+			upload_test_part "${fn}" "${reply}" "${i}" || return 1
+			etag="${PART_ETAG}"
+		fi
+		etags=$(echo "${etags}" | jq ". + [{\"part_number\": ${n}, \"etag\": ${etag}}]")
+	done
+	rm -f "${fn}.part"
+
+	#
+	# Complete multipart upload
+	#
+	url="http://${HOST}:${PORT}/api/v1/files/${id}/upload/complete"
+	echo "Running: curl -X POST -H ${HEADER} -d {\"parts\": ${etags}} ${url}"
+	reply=$(curl -X POST -H "${HEADER}" -d "{\"parts\": ${etags}}" "${url}" 2>/dev/null)
+	rc=$?
+	if [ "${rc}" -ne 0 ]; then
+		echo "### Error ###"
+		return 1
+	fi
+	msg=$(echo "${reply}" | jq)
+	rfn="testresults/results-create-multipart-upload-${TS}-step-4-complete.lst"
+	echo "Result: ${rc} -> ${msg}" | tee "${rfn}"
+	status=$(echo "${msg}" | jq '.data[0].status' | tr -d \")
+	if [ "${status}" != "U" ]; then
+		echo "### Error ###"
+		return 1
+	fi
+
+	echo "Success"
+
+	return 0
+}
+
+n=${1:-1}
+
+while true
+do
+	if test "${n}" -gt 0
+	then
+		run_test "$n"
+		rc=$?
+		if [ "${rc}" -ne 0 ]; then
+			echo "### Stop ###"
+		fi
+	else
+		break
+	fi
+	n=$(( n - 1 ))
+done
+
+exit 0
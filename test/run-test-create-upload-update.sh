@@ -50,7 +50,7 @@ function run_test() {
 		echo "### Error ###"
 		return 1
 	fi
-	id=$(echo "${reply}" | jq '.data[0].ID' | tr -d \")
+	id=$(echo "${reply}" | jq '.data[0].id' | tr -d \")
 	
 	#
 	# Upload test file testing the presigned URL
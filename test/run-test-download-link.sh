@@ -0,0 +1,116 @@
+#!/bin/bash
+
+TS=$(date +"%Y%m%d%H%M%S")
+HOST=localhost
+PORT=1234
+HEADER="Content-Type: application/json"
+TENANT=xxx
+DEVICE=yyy
+BLOCK_SIZE=1
+DATA=
+TMP_FILE_NAME=
+
+# shellcheck source=test_tools.sh
+. test_tools.sh
+
+# Perform one test, create and upload the file, make the one time download link
+# and check it can be used only once
+function run_test() {
+	local testno fn rfn status rc reply msg bfn url id token code
+
+	testno="$1"
+
+	# Make a test file with random content of size N blocks
+	make_test_file "${testno}" "${BLOCK_SIZE}"
+	fn="${TMP_FILE_NAME}"
+	bfn="$(basename "${fn}")"
+	make_control_file "${bfn}"
+	DATA="${CONTROL_FILE_NAME}"
+
+	#
+	# Send request to the fs API
+	#
+	url="http://${HOST}:${PORT}/api/v1/files?tenant=${TENANT}&device=${DEVICE}"
+	echo "Running: curl -X POST -H ${HEADER} -d@${DATA} ${url}"
+	reply=$(curl -X POST -H "${HEADER}" -d@"${DATA}" "${url}" 2>/dev/null)
+	rc=$?
+	if [ "${rc}" -ne 0 ]; then
+		echo "### Error ###"
+		return 1
+	fi
+	msg=$(echo "${reply}" | jq)
+	rfn="testresults/results-download-link-${TS}-step-1-create.lst"
+	echo "Result: ${rc} -> ${msg}" | tee "${rfn}"
+	status=$(echo "${msg}" | jq '.status')
+	if [ "${status}" != "true" ]; then
+		echo "### Error ###"
+		return 1
+	fi
+	id=$(echo "${reply}" | jq '.data[0].id' | tr -d \")
+
+	#
+	# Upload test file and verify it so the link may be made
+	#
+	upload_test_file "${fn}" "${reply}" || return 1
+	url="http://${HOST}:${PORT}/api/v1/files/${id}/verify"
+	echo "Running: curl -X POST ${url}"
+	reply=$(curl -X POST "${url}" 2>/dev/null)
+	msg=$(echo "${reply}" | jq)
+	rfn="testresults/results-download-link-${TS}-step-2-verify.lst"
+	echo "Result: ${rc} -> ${msg}" | tee "${rfn}"
+
+	#
+	# Make the one time download link
+	#
+	url="http://${HOST}:${PORT}/api/v1/files/${id}/links?device=${DEVICE}"
+	echo "Running: curl -X POST -H ${HEADER} -d {\"max_uses\": 1} ${url}"
+	reply=$(curl -X POST -H "${HEADER}" -d '{"max_uses": 1}' "${url}" 2>/dev/null)
+	msg=$(echo "${reply}" | jq)
+	rfn="testresults/results-download-link-${TS}-step-3-link.lst"
+	echo "Result: ${rc} -> ${msg}" | tee "${rfn}"
+	token=$(echo "${reply}" | jq '.data[0].token' | tr -d \")
+	if [ -z "${token}" ] || [ "${token}" == "null" ]; then
+		echo "### Error ###"
+		return 1
+	fi
+
+	#
+	# The first use redirects to the file, the second one is refused
+	#
+	url="http://${HOST}:${PORT}/d/${token}"
+	echo "Running: curl -L -o ${fn}.download ${url}"
+	curl -L -o "${fn}.download" "${url}" 2>/dev/null
+	if ! cmp -s "${fn}" "${fn}.download"; then
+		echo "### Error ###"
+		return 1
+	fi
+	code=$(curl -o /dev/null -w "%{http_code}" "${url}" 2>/dev/null)
+	echo "Result: second use -> ${code}"
+	if [ "${code}" != "410" ]; then
+		echo "### Error ###"
+		return 1
+	fi
+
+	echo "Success"
+
+	return 0
+}
+
+n=${1:-1}
+
+while true
+do
+	if test "${n}" -gt 0
+	then
+		run_test "$n"
+		rc=$?
+		if [ "${rc}" -ne 0 ]; then
+			echo "### Stop ###"
+		fi
+	else
+		break
+	fi
+	n=$(( n - 1 ))
+done
+
+exit 0
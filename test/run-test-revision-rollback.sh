@@ -0,0 +1,130 @@
+#!/bin/bash
+
+TS=$(date +"%Y%m%d%H%M%S")
+HOST=localhost
+PORT=1234
+HEADER="Content-Type: application/json"
+TENANT=xxx
+DEVICE=yyy
+BLOCK_SIZE=1
+DATA=
+TMP_FILE_NAME=
+
+# shellcheck source=test_tools.sh
+. test_tools.sh
+
+# Create, upload and verify the revision of the file of the name from the test
+# file, the reply of the create is kept in REPLY
+REPLY=
+function create_revision() {
+	local step name fn rc msg rfn status id url
+	step="$1"
+	name="$2"
+	fn="$3"
+
+	printf "$CONTROL_FILE_FORMAT" "${name}" "$(sha256sum "${fn}" | awk '{print $1}')" "$(stat --format=%s "${fn}")" >"${fn}.json"
+	url="http://${HOST}:${PORT}/api/v1/files?tenant=${TENANT}&device=${DEVICE}"
+	echo "Running: curl -X POST -H ${HEADER} -d@${fn}.json ${url}"
+	REPLY=$(curl -X POST -H "${HEADER}" -d@"${fn}.json" "${url}" 2>/dev/null)
+	rc=$?
+	if [ "${rc}" -ne 0 ]; then
+		return 1
+	fi
+	msg=$(echo "${REPLY}" | jq)
+	rfn="testresults/results-revision-rollback-${TS}-step-${step}-create.lst"
+	echo "Result: ${rc} -> ${msg}" | tee "${rfn}"
+	status=$(echo "${msg}" | jq '.status')
+	if [ "${status}" != "true" ]; then
+		return 1
+	fi
+	id=$(echo "${REPLY}" | jq '.data[0].id' | tr -d \")
+
+	upload_test_file "${fn}" "${REPLY}" || return 1
+	url="http://${HOST}:${PORT}/api/v1/files/${id}/verify"
+	echo "Running: curl -X POST ${url}"
+	msg=$(curl -X POST "${url}" 2>/dev/null | jq)
+	rfn="testresults/results-revision-rollback-${TS}-step-${step}-verify.lst"
+	echo "Result: ${rc} -> ${msg}" | tee "${rfn}"
+
+	return 0
+}
+
+# Perform one test, upload two revisions of the file, roll back to the first
+# one and check it comes back as the third revision with the first content
+function run_test() {
+	local testno fn1 fn2 name rfn reply msg id url revision cksum
+
+	testno="$1"
+
+	make_test_file "${testno}" "${BLOCK_SIZE}"
+	fn1="${TMP_FILE_NAME}"
+	make_test_file "${testno}" "${BLOCK_SIZE}"
+	fn2="${TMP_FILE_NAME}"
+	name="$(basename "${fn1}")"
+
+	#
+	# The files of the same name are the revisions numbered one after the other
+	#
+	create_revision 1 "${name}" "${fn1}" || { echo "### Error ###"; return 1; }
+	revision=$(echo "${REPLY}" | jq '.data[0].revision')
+	create_revision 2 "${name}" "${fn2}" || { echo "### Error ###"; return 1; }
+	id=$(echo "${REPLY}" | jq '.data[0].id' | tr -d \")
+	if [ "$(echo "${REPLY}" | jq '.data[0].revision')" != "$(( revision + 1 ))" ]; then
+		echo "### Error ###"
+		return 1
+	fi
+
+	#
+	# The rollback copies the first revision to the next one
+	#
+	url="http://${HOST}:${PORT}/api/v1/files/${id}/revisions/${revision}/rollback"
+	echo "Running: curl -X POST ${url}"
+	reply=$(curl -X POST "${url}" 2>/dev/null)
+	msg=$(echo "${reply}" | jq)
+	rfn="testresults/results-revision-rollback-${TS}-step-3-rollback.lst"
+	echo "Result: -> ${msg}" | tee "${rfn}"
+	if [ "$(echo "${reply}" | jq '.data[0].revision')" != "$(( revision + 2 ))" ]; then
+		echo "### Error ###"
+		return 1
+	fi
+
+	#
+	# The latest revision has the content of the first one
+	#
+	url="http://${HOST}:${PORT}/api/v1/files/${id}/revisions"
+	echo "Running: curl ${url}"
+	reply=$(curl "${url}" 2>/dev/null)
+	msg=$(echo "${reply}" | jq)
+	rfn="testresults/results-revision-rollback-${TS}-step-4-revisions.lst"
+	echo "Result: -> ${msg}" | tee "${rfn}"
+	cksum=$(sha256sum "${fn1}" | awk '{print $1}' | xxd -r -p | base64)
+	if [ "$(echo "${reply}" | jq -r '.data[0].revision')" != "$(( revision + 2 ))" ] ||
+		[ "$(echo "${reply}" | jq -r '.data[0].check_sum')" != "${cksum}" ] ||
+		[ "$(echo "${reply}" | jq -r '.data[0].status')" != "C" ]; then
+		echo "### Error ###"
+		return 1
+	fi
+
+	echo "Success"
+
+	return 0
+}
+
+n=${1:-1}
+
+while true
+do
+	if test "${n}" -gt 0
+	then
+		run_test "$n"
+		rc=$?
+		if [ "${rc}" -ne 0 ]; then
+			echo "### Stop ###"
+		fi
+	else
+		break
+	fi
+	n=$(( n - 1 ))
+done
+
+exit 0
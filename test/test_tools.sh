@@ -33,13 +33,19 @@ make_test_file() {
 
 # Extract key/value form parameters from the reply, format curl command and run it
 upload_test_file() {
-	local reply tmp_file_name
+	local reply tmp_file_name cksum headers
 	tmp_file_name="$1"
 	reply="$2"
 	url=$(echo "${reply}" | jq '.data[0].url' | tr -d \")
+	# The checksum is signed into the url so it must be sent base64 encoded
+	cksum=$(openssl dgst -sha256 -binary "${tmp_file_name}" | base64)
+	# The signed headers like the ones of encryption must be sent as given
+	headers=()
+	while read -r header; do
+		[ -n "${header}" ] && headers+=(-H "${header}")
+	done < <(echo "${reply}" | jq -r '.data[0].access.headers // {} | to_entries[] | "\(.key): \(.value)"')
 	# Curl magic to put the file size in the request header constraints
-	cmd="curl -X PUT -T ${tmp_file_name} -D - ${url}"
-	echo "Running: $cmd"
-	$cmd
+	echo "Running: curl -X PUT -H x-amz-checksum-sha256:${cksum} ${headers[*]} -T ${tmp_file_name} -D - ${url}"
+	curl -X PUT -H "x-amz-checksum-sha256: ${cksum}" "${headers[@]}" -T "${tmp_file_name}" -D - "${url}"
 	return $?
 }	